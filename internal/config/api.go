@@ -0,0 +1,586 @@
+package config
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gabeportillo51/blog_aggregator/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+type apiServer struct {
+	state *State
+}
+
+type apiUser struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name      string    `json:"name"`
+}
+
+type apiFeed struct {
+	ID            uuid.UUID  `json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	Name          string     `json:"name"`
+	Url           string     `json:"url"`
+	SiteUrl       string     `json:"site_url,omitempty"`
+	UserID        uuid.UUID  `json:"user_id"`
+	CreatedBy     string     `json:"created_by,omitempty"`
+	LastFetchedAt *time.Time `json:"last_fetched_at"`
+	LastSuccessAt *time.Time `json:"last_success_at"`
+}
+
+type apiFollow struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	FeedID    uuid.UUID `json:"feed_id"`
+	FeedName  string    `json:"feed_name"`
+	FeedUrl   string    `json:"feed_url,omitempty"`
+	Category  string    `json:"category,omitempty"`
+}
+
+type apiPost struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Title       string    `json:"title"`
+	Url         string    `json:"url"`
+	Description string    `json:"description"`
+	Author      string    `json:"author,omitempty"`
+	PublishedAt time.Time `json:"published_at"`
+	FeedID      uuid.UUID `json:"feed_id"`
+	FeedName    string    `json:"feed_name"`
+	Read        bool      `json:"read"`
+}
+
+type apiPage struct {
+	Items  interface{} `json:"items"`
+	Limit  int32       `json:"limit"`
+	Offset int32       `json:"offset"`
+}
+
+func HandlerServe(s *State, cmd Command) error {
+	if len(cmd.Args) > 1 {
+		return errors.New("error: incorrect number of arguments provided to 'serve' command")
+	}
+	addr := "127.0.0.1:8080"
+	if len(cmd.Args) == 1 {
+		addr = cmd.Args[0]
+	}
+	api := &apiServer{state: s}
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           api.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	fmt.Printf("Serving the gator API on %s\n", addr)
+	fmt.Printf("Authenticate /api/ requests with a token from 'gator apitoken'\n")
+	return server.ListenAndServe()
+}
+
+func (a *apiServer) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/users", a.withToken(a.handleListUsers))
+	mux.HandleFunc("POST /api/users", a.withToken(a.handleCreateUser))
+	mux.HandleFunc("GET /api/users/{name}", a.withUser(a.handleGetUser))
+	mux.HandleFunc("GET /api/feeds", a.withToken(a.handleListFeeds))
+	mux.HandleFunc("POST /api/feeds", a.withToken(a.handleCreateFeed))
+	mux.HandleFunc("GET /api/feeds/{id}", a.withToken(a.handleGetFeed))
+	mux.HandleFunc("GET /api/users/{name}/follows", a.withUser(a.handleListFollows))
+	mux.HandleFunc("POST /api/users/{name}/follows", a.withUser(a.handleCreateFollow))
+	mux.HandleFunc("DELETE /api/users/{name}/follows/{feed_id}", a.withUser(a.handleDeleteFollow))
+	mux.HandleFunc("GET /api/users/{name}/posts", a.withUser(a.handleListPosts))
+	mux.HandleFunc("PUT /api/users/{name}/posts/{id}/read", a.withUser(a.handleMarkRead))
+	mux.HandleFunc("DELETE /api/users/{name}/posts/{id}/read", a.withUser(a.handleMarkUnread))
+	mux.HandleFunc("GET /output/{token}/{file}", a.handleOutputFeed)
+	mux.HandleFunc("/fever/", a.handleFever)
+	a.greaderRoutes(mux)
+	return mux
+}
+
+// withToken authenticates the request with the bearer token issued by the
+// apitoken command; only its hash is stored, so the lookup hashes it first
+func (a *apiServer) withToken(handler func(http.ResponseWriter, *http.Request, database.User)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || strings.TrimSpace(token) == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			respondWithError(w, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+		user, err := a.state.Db.GetUserByAPITokenHash(r.Context(), sql.NullString{
+			String: hashToken(strings.TrimSpace(token)),
+			Valid:  true,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			respondWithError(w, http.StatusUnauthorized, "invalid bearer token")
+			return
+		} else if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		handler(w, r, user)
+	}
+}
+
+// withUser also requires the {name} in the path to be the token's owner
+func (a *apiServer) withUser(handler func(http.ResponseWriter, *http.Request, database.User)) http.HandlerFunc {
+	return a.withToken(func(w http.ResponseWriter, r *http.Request, user database.User) {
+		if r.PathValue("name") != user.Name {
+			respondWithError(w, http.StatusForbidden, "token does not belong to this user")
+			return
+		}
+		handler(w, r, user)
+	})
+}
+
+func newToken() (string, error) {
+	buf := make([]byte, 16)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func HandlerAPIToken(s *State, cmd Command, user database.User) error {
+	reset := false
+	switch {
+	case len(cmd.Args) == 0:
+	case len(cmd.Args) == 1 && cmd.Args[0] == "--reset":
+		reset = true
+	default:
+		return errors.New("error: usage is 'apitoken [--reset]'")
+	}
+	if user.ApiTokenHash.Valid && !reset {
+		fmt.Printf("%s already has an API token; run 'apitoken --reset' to replace it\n", user.Name)
+		return nil
+	}
+	token, err := newToken()
+	if err != nil {
+		return fmt.Errorf("error generating API token: %w", err)
+	}
+	err = s.Db.UpdateUserAPITokenHash(context.Background(), database.UpdateUserAPITokenHashParams{
+		ID:           user.ID,
+		UpdatedAt:    time.Now(),
+		ApiTokenHash: sql.NullString{String: hashToken(token), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("error saving API token: %w", err)
+	}
+	fmt.Printf("API token for %s: %s\n", user.Name, token)
+	fmt.Printf("It is only shown once; send it as 'Authorization: Bearer <token>'\n")
+	return nil
+}
+
+func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+func respondWithError(w http.ResponseWriter, code int, msg string) {
+	respondWithJSON(w, code, map[string]string{"error": msg})
+}
+
+func respondWithDBError(w http.ResponseWriter, err error, notFound string) {
+	var pqErr *pq.Error
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		respondWithError(w, http.StatusNotFound, notFound)
+	case errors.As(err, &pqErr) && pqErr.Code == "23505":
+		respondWithError(w, http.StatusConflict, "resource already exists")
+	default:
+		respondWithError(w, http.StatusInternalServerError, "database error")
+	}
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(v)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid request body")
+		return false
+	}
+	return true
+}
+
+func pagination(r *http.Request) (int32, int32, error) {
+	limit := int64(defaultPageSize)
+	offset := int64(0)
+	var err error
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err = strconv.ParseInt(value, 10, 32)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return 0, 0, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+		}
+	}
+	if value := r.URL.Query().Get("offset"); value != "" {
+		offset, err = strconv.ParseInt(value, 10, 32)
+		if err != nil || offset < 0 {
+			return 0, 0, errors.New("offset must be a non-negative integer")
+		}
+	}
+	return int32(limit), int32(offset), nil
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func toAPIUser(user database.User) apiUser {
+	return apiUser{
+		ID:        user.ID,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+		Name:      user.Name,
+	}
+}
+
+func toAPIFeed(feed database.Feed) apiFeed {
+	return apiFeed{
+		ID:            feed.ID,
+		CreatedAt:     feed.CreatedAt,
+		UpdatedAt:     feed.UpdatedAt,
+		Name:          feed.Name,
+		Url:           feed.Url,
+		SiteUrl:       feed.SiteUrl.String,
+		UserID:        feed.UserID,
+		LastFetchedAt: nullTimePtr(feed.LastFetchedAt),
+		LastSuccessAt: nullTimePtr(feed.LastSuccessAt),
+	}
+}
+
+func (a *apiServer) handleListUsers(w http.ResponseWriter, r *http.Request, _ database.User) {
+	limit, offset, err := pagination(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	users, err := a.state.Db.ListUsersPage(r.Context(), database.ListUsersPageParams{
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		respondWithDBError(w, err, "")
+		return
+	}
+	items := make([]apiUser, 0, len(users))
+	for _, user := range users {
+		items = append(items, toAPIUser(user))
+	}
+	respondWithJSON(w, http.StatusOK, apiPage{Items: items, Limit: limit, Offset: offset})
+}
+
+func (a *apiServer) handleCreateUser(w http.ResponseWriter, r *http.Request, _ database.User) {
+	var body struct {
+		Name string `json:"name"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if body.Name == "" {
+		respondWithError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	user, err := a.state.Db.CreateUser(r.Context(), database.CreateUserParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Name:      body.Name,
+	})
+	if err != nil {
+		respondWithDBError(w, err, "")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, toAPIUser(user))
+}
+
+func (a *apiServer) handleGetUser(w http.ResponseWriter, r *http.Request, user database.User) {
+	respondWithJSON(w, http.StatusOK, toAPIUser(user))
+}
+
+func (a *apiServer) handleListFeeds(w http.ResponseWriter, r *http.Request, _ database.User) {
+	limit, offset, err := pagination(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	feeds, err := a.state.Db.ListFeedsPage(r.Context(), database.ListFeedsPageParams{
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		respondWithDBError(w, err, "")
+		return
+	}
+	items := make([]apiFeed, 0, len(feeds))
+	for _, feed := range feeds {
+		items = append(items, apiFeed{
+			ID:            feed.ID,
+			CreatedAt:     feed.CreatedAt,
+			UpdatedAt:     feed.UpdatedAt,
+			Name:          feed.Name,
+			Url:           feed.Url,
+			SiteUrl:       feed.SiteUrl.String,
+			UserID:        feed.UserID,
+			CreatedBy:     feed.UserName.String,
+			LastFetchedAt: nullTimePtr(feed.LastFetchedAt),
+			LastSuccessAt: nullTimePtr(feed.LastSuccessAt),
+		})
+	}
+	respondWithJSON(w, http.StatusOK, apiPage{Items: items, Limit: limit, Offset: offset})
+}
+
+func (a *apiServer) handleCreateFeed(w http.ResponseWriter, r *http.Request, user database.User) {
+	var body struct {
+		Name string `json:"name"`
+		Url  string `json:"url"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if body.Name == "" || body.Url == "" {
+		respondWithError(w, http.StatusBadRequest, "name and url are required")
+		return
+	}
+	feed, err := a.state.Db.CreateFeed(r.Context(), database.CreateFeedParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Name:      body.Name,
+		Url:       body.Url,
+		UserID:    user.ID,
+	})
+	if err != nil {
+		respondWithDBError(w, err, "")
+		return
+	}
+	_, err = a.state.Db.CreateFeedFollow(r.Context(), database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		FeedID:    feed.ID,
+	})
+	if err != nil {
+		respondWithDBError(w, err, "")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, toAPIFeed(feed))
+}
+
+func (a *apiServer) handleGetFeed(w http.ResponseWriter, r *http.Request, _ database.User) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid feed id")
+		return
+	}
+	feed, err := a.state.Db.GetFeedFromID(r.Context(), id)
+	if err != nil {
+		respondWithDBError(w, err, "feed not found")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, toAPIFeed(feed))
+}
+
+func (a *apiServer) handleListFollows(w http.ResponseWriter, r *http.Request, user database.User) {
+	limit, offset, err := pagination(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	feed_follows, err := a.state.Db.ListFeedFollowsPage(r.Context(), database.ListFeedFollowsPageParams{
+		UserID: user.ID,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		respondWithDBError(w, err, "")
+		return
+	}
+	items := make([]apiFollow, 0, len(feed_follows))
+	for _, feed_follow := range feed_follows {
+		items = append(items, apiFollow{
+			ID:        feed_follow.ID,
+			CreatedAt: feed_follow.CreatedAt,
+			FeedID:    feed_follow.FeedID,
+			FeedName:  feed_follow.FeedName,
+			FeedUrl:   feed_follow.FeedUrl,
+			Category:  feed_follow.Category.String,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, apiPage{Items: items, Limit: limit, Offset: offset})
+}
+
+func (a *apiServer) handleCreateFollow(w http.ResponseWriter, r *http.Request, user database.User) {
+	var body struct {
+		Url      string `json:"url"`
+		Category string `json:"category"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	feed, err := a.state.Db.GetFeed(r.Context(), body.Url)
+	if err != nil {
+		respondWithDBError(w, err, "feed not found")
+		return
+	}
+	feed_follow, err := a.state.Db.CreateFeedFollow(r.Context(), database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		FeedID:    feed.ID,
+		Category: sql.NullString{
+			String: body.Category,
+			Valid:  body.Category != "",
+		},
+	})
+	if err != nil {
+		respondWithDBError(w, err, "")
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, apiFollow{
+		ID:        feed_follow.ID,
+		CreatedAt: feed_follow.CreatedAt,
+		FeedID:    feed_follow.FeedID,
+		FeedName:  feed_follow.FeedName,
+		FeedUrl:   feed.Url,
+		Category:  feed_follow.Category.String,
+	})
+}
+
+func (a *apiServer) handleDeleteFollow(w http.ResponseWriter, r *http.Request, user database.User) {
+	feed_id, err := uuid.Parse(r.PathValue("feed_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid feed id")
+		return
+	}
+	err = a.state.Db.DeleteFeedFollow(r.Context(), database.DeleteFeedFollowParams{
+		UserID: user.ID,
+		FeedID: feed_id,
+	})
+	if err != nil {
+		respondWithDBError(w, err, "")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *apiServer) handleListPosts(w http.ResponseWriter, r *http.Request, user database.User) {
+	limit, offset, err := pagination(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	posts, err := a.state.Db.GetPostsForUser(r.Context(), database.GetPostsForUserParams{
+		UserID:     user.ID,
+		UnreadOnly: r.URL.Query().Get("unread") == "true",
+		PostLimit:  limit,
+		PostOffset: offset,
+	})
+	if err != nil {
+		respondWithDBError(w, err, "")
+		return
+	}
+	items := make([]apiPost, 0, len(posts))
+	for _, post := range posts {
+		items = append(items, apiPost{
+			ID:          post.ID,
+			CreatedAt:   post.CreatedAt,
+			UpdatedAt:   post.UpdatedAt,
+			Title:       post.Title,
+			Url:         post.Url,
+			Description: post.Description.String,
+			Author:      post.Author.String,
+			PublishedAt: post.PublishedAt,
+			FeedID:      post.FeedID,
+			FeedName:    post.FeedName,
+			Read:        post.IsRead,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, apiPage{Items: items, Limit: limit, Offset: offset})
+}
+
+func (a *apiServer) postFromPath(w http.ResponseWriter, r *http.Request) (database.GetPostRow, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid post id")
+		return database.GetPostRow{}, false
+	}
+	post, err := a.state.Db.GetPost(r.Context(), id)
+	if err != nil {
+		respondWithDBError(w, err, "post not found")
+		return database.GetPostRow{}, false
+	}
+	return post, true
+}
+
+func (a *apiServer) handleMarkRead(w http.ResponseWriter, r *http.Request, user database.User) {
+	post, ok := a.postFromPath(w, r)
+	if !ok {
+		return
+	}
+	err := a.state.Db.MarkPostRead(r.Context(), database.MarkPostReadParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		PostID:    post.ID,
+	})
+	if err != nil {
+		respondWithDBError(w, err, "")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *apiServer) handleMarkUnread(w http.ResponseWriter, r *http.Request, user database.User) {
+	post, ok := a.postFromPath(w, r)
+	if !ok {
+		return
+	}
+	err := a.state.Db.MarkPostUnread(r.Context(), database.MarkPostUnreadParams{
+		UserID: user.ID,
+		PostID: post.ID,
+	})
+	if err != nil {
+		respondWithDBError(w, err, "")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// newTestAPI serves the API from a fake database holding one user, gabe,
+// whose bearer token is "secret-token"
+func newTestAPI(t *testing.T, rows func(query string, args []driver.Value) [][]driver.Value) (*fakeDB, http.Handler) {
+	t.Helper()
+	user := []driver.Value{uuid.New().String(), time.Now(), time.Now(), "gabe", hashToken("secret-token"), nil, nil, nil}
+	db, queries := newFakeDB(t, func(query string, args []driver.Value) [][]driver.Value {
+		if strings.HasPrefix(query, "-- name: GetUserByAPITokenHash ") {
+			if args[0] == hashToken("secret-token") {
+				return [][]driver.Value{user}
+			}
+			return nil
+		}
+		if rows == nil {
+			return nil
+		}
+		return rows(query, args)
+	})
+	return db, (&apiServer{state: &State{Db: queries}}).routes()
+}
+
+func TestAPIAuthentication(t *testing.T) {
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		wantStatus    int
+	}{
+		{"no header", "/api/users/gabe", "", http.StatusUnauthorized},
+		{"not bearer", "/api/users/gabe", "Basic Z2FiZTpzZWNyZXQ=", http.StatusUnauthorized},
+		{"empty token", "/api/users/gabe", "Bearer  ", http.StatusUnauthorized},
+		{"unknown token", "/api/users/gabe", "Bearer other-token", http.StatusUnauthorized},
+		{"stored hash", "/api/users/gabe", "Bearer " + hashToken("secret-token"), http.StatusUnauthorized},
+		{"owner", "/api/users/gabe", "Bearer secret-token", http.StatusOK},
+		{"padded token", "/api/users/gabe", "Bearer  secret-token ", http.StatusOK},
+		{"someone else", "/api/users/alice", "Bearer secret-token", http.StatusForbidden},
+		{"token only", "/api/feeds", "Bearer secret-token", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, api := newTestAPI(t, nil)
+			r := httptest.NewRequest("GET", tt.path, nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			api.ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("WWW-Authenticate = %q, want Bearer", w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestPagination(t *testing.T) {
+	tests := []struct {
+		query      string
+		wantLimit  int32
+		wantOffset int32
+		wantErr    bool
+	}{
+		{"", defaultPageSize, 0, false},
+		{"limit=1", 1, 0, false},
+		{"limit=100&offset=40", maxPageSize, 40, false},
+		{"offset=0", defaultPageSize, 0, false},
+		{"limit=0", 0, 0, true},
+		{"limit=101", 0, 0, true},
+		{"limit=-5", 0, 0, true},
+		{"limit=ten", 0, 0, true},
+		{"offset=-1", 0, 0, true},
+		{"offset=2147483648", 0, 0, true},
+		{"offset=1.5", 0, 0, true},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/api/feeds?"+tt.query, nil)
+		limit, offset, err := pagination(r)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("pagination(%q) error = %v, want error %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if limit != tt.wantLimit || offset != tt.wantOffset {
+			t.Errorf("pagination(%q) = %d, %d, want %d, %d", tt.query, limit, offset, tt.wantLimit, tt.wantOffset)
+		}
+	}
+}
+
+func TestListFollowsPage(t *testing.T) {
+	feedID := uuid.New()
+	db, api := newTestAPI(t, func(query string, args []driver.Value) [][]driver.Value {
+		if strings.HasPrefix(query, "-- name: ListFeedFollowsPage ") {
+			return [][]driver.Value{{uuid.New().String(), time.Now(), feedID.String(), "tech", "Example", "https://example.com/rss"}}
+		}
+		return nil
+	})
+	r := httptest.NewRequest("GET", "/api/users/gabe/follows?limit=5&offset=10", nil)
+	r.Header.Set("Authorization", "Bearer secret-token")
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+
+	// the page is cut in the query rather than from every follow in memory
+	var page *fakeCall
+	for i, call := range db.calls {
+		if strings.HasPrefix(call.query, "-- name: ListFeedFollowsPage ") {
+			page = &db.calls[i]
+		}
+	}
+	if page == nil {
+		t.Fatalf("ran %v, want ListFeedFollowsPage", db.names())
+	}
+	if page.args[1] != int64(5) || page.args[2] != int64(10) {
+		t.Errorf("ListFeedFollowsPage limit, offset = %v, %v, want 5, 10", page.args[1], page.args[2])
+	}
+	var body struct {
+		Items  []apiFollow `json:"items"`
+		Limit  int32       `json:"limit"`
+		Offset int32       `json:"offset"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &body)
+	if err != nil {
+		t.Fatalf("invalid JSON response: %v", err)
+	}
+	if len(body.Items) != 1 || body.Items[0].FeedID != feedID || body.Items[0].Category != "tech" {
+		t.Errorf("items = %+v, want the one follow", body.Items)
+	}
+	if body.Limit != 5 || body.Offset != 10 {
+		t.Errorf("limit, offset = %d, %d, want 5, 10", body.Limit, body.Offset)
+	}
+}
+
+func TestCreateFeedRejectsUserField(t *testing.T) {
+	db, api := newTestAPI(t, nil)
+	body := `{"name":"Example","url":"https://example.com/rss","user":"alice"}`
+	r := httptest.NewRequest("POST", "/api/feeds", strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer secret-token")
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	for _, name := range db.names() {
+		if name == "CreateFeed" {
+			t.Errorf("CreateFeed ran for a body naming another user")
+		}
+	}
+}
@@ -2,17 +2,21 @@ package config
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
-	"encoding/xml"
 	"errors"
 	"fmt"
-	"html"
 	"io"
+	"math"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gabeportillo51/blog_aggregator/internal/database"
@@ -20,13 +24,25 @@ import (
 )
 
 type Config struct {
-	DBUrl string `json:"db_url"`
-	User  string `json:"current_user_name"`
+	DBUrl               string `json:"db_url"`
+	User                string `json:"current_user_name"`
+	MinFetchInterval    string `json:"min_fetch_interval,omitempty"`
+	MaxFetchInterval    string `json:"max_fetch_interval,omitempty"`
+	HostConcurrency     int    `json:"host_concurrency,omitempty"`
+	HostRequestInterval string `json:"host_request_interval,omitempty"`
+	ConnectTimeout      string `json:"connect_timeout,omitempty"`
+	FetchTimeout        string `json:"fetch_timeout,omitempty"`
+	MaxFeedSize         int64  `json:"max_feed_size,omitempty"`
+	MaxRedirects        *int   `json:"max_redirects,omitempty"`
+	UserAgent           string `json:"user_agent,omitempty"`
 }
 
 type State struct {
-	Db  *database.Queries
-	Cfg *Config
+	Db      *database.Queries
+	Conn    *sql.DB
+	Cfg     *Config
+	Format  string
+	Fetcher *Fetcher
 }
 
 type Command struct {
@@ -41,72 +57,328 @@ type Commands struct {
 type RSSFeed struct {
 	Channel struct {
 		Title       string    `xml:"title"`
-		Link        string    `xml:"link"`
+		Link        XMLLinks  `xml:"link"`
 		Description string    `xml:"description"`
+		TTL         string    `xml:"ttl"`
+		SkipHours   []string  `xml:"skipHours>hour"`
+		SkipDays    []string  `xml:"skipDays>day"`
 		Item        []RSSItem `xml:"item"`
 	} `xml:"channel"`
 }
 
 type RSSItem struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
+	Title       string   `xml:"title"`
+	Link        XMLLinks `xml:"link"`
+	Description string   `xml:"description"`
+	PubDate     string   `xml:"pubDate"`
+	GUID        string   `xml:"guid"`
+	Author      string   `xml:"author"`
+	Date        string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
 }
 
-func ScrapeFeeds(s *State) error {
-	feed, err := s.Db.GetNextFeedToFetch(context.Background())
-	if err != nil {
-		return errors.New("error getting next feed to fetch")
-	}
+type ScrapeSummary struct {
+	Feeds        int
+	NewPosts     int
+	UpdatedPosts int
+	NotModified  int
+	Failed       int
+	Paused       int
+	Gone         int
+	Interrupted  int
+}
+
+func (sum *ScrapeSummary) Add(other ScrapeSummary) {
+	sum.Feeds += other.Feeds
+	sum.NewPosts += other.NewPosts
+	sum.UpdatedPosts += other.UpdatedPosts
+	sum.NotModified += other.NotModified
+	sum.Failed += other.Failed
+	sum.Paused += other.Paused
+	sum.Gone += other.Gone
+	sum.Interrupted += other.Interrupted
+}
+
+func ScrapeFeeds(ctx context.Context, s *State, concurrency int) (ScrapeSummary, error) {
+	var summary ScrapeSummary
 	currentTime := sql.NullTime{
 		Time:  time.Now(),
 		Valid: true,
 	}
-	mark := database.MarkFeedFetchedParams{
+	// the claim is a lease rather than a row lock, so a second agg process
+	// skips these feeds until this run records them or stops renewing it
+	token := uuid.NullUUID{UUID: uuid.New(), Valid: true}
+	claim := database.ClaimFeedsToFetchParams{
 		UpdatedAt:     time.Now(),
 		LastFetchedAt: currentTime,
-		ID:            feed.ID,
+		ClaimedUntil: sql.NullTime{
+			Time:  time.Now().Add(feedClaimLease),
+			Valid: true,
+		},
+		ClaimToken: token,
+		Limit:      int32(concurrency),
 	}
-	err1 := s.Db.MarkFeedFetched(context.Background(), mark)
-	if err1 != nil {
-		return errors.New("error marking feed")
+	feeds, err := s.Db.ClaimFeedsToFetch(ctx, claim)
+	if err != nil {
+		return summary, errors.New("error claiming feeds to fetch")
+	}
+	renewing := make(chan struct{})
+	defer close(renewing)
+	go renewFeedClaims(ctx, s, token, renewing)
+	jobs := make(chan database.Feed)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for feed := range jobs {
+				outcome := processFeed(ctx, s, feed)
+				mu.Lock()
+				summary.Add(outcome)
+				mu.Unlock()
+			}
+		}()
+	}
+dispatch:
+	for i, feed := range feeds {
+		select {
+		case jobs <- feed:
+		case <-ctx.Done():
+			// hand the feeds no worker picked up back to the next run
+			for _, unclaimed := range feeds[i:] {
+				err := s.Db.ReleaseFeedClaim(context.WithoutCancel(ctx), database.ReleaseFeedClaimParams{
+					ID:         unclaimed.ID,
+					ClaimToken: unclaimed.ClaimToken,
+				})
+				if err != nil {
+					fmt.Printf("Error releasing claim on feed '%s': %v\n", unclaimed.Name, err)
+				}
+			}
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return summary, nil
+}
+
+const feedClaimLease = 2 * time.Minute
+
+// renewFeedClaims keeps extending the lease on every feed this run still
+// holds, however long they wait for a worker or a host slot, until done is
+// closed; feeds drop out of it as soon as they are recorded or released
+func renewFeedClaims(ctx context.Context, s *State, token uuid.NullUUID, done <-chan struct{}) {
+	ticker := time.NewTicker(feedClaimLease / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		err := s.Db.RenewFeedClaims(context.WithoutCancel(ctx), database.RenewFeedClaimsParams{
+			ClaimedUntil: sql.NullTime{Time: time.Now().Add(feedClaimLease), Valid: true},
+			ClaimToken:   token,
+		})
+		if err != nil {
+			fmt.Printf("Error renewing feed claims: %v\n", err)
+		}
+	}
+}
+
+func processFeed(ctx context.Context, s *State, feed database.Feed) ScrapeSummary {
+	result, summary, err := scrapeFeed(ctx, s, feed)
+	summary.Feeds = 1
+	if err != nil && ctx.Err() != nil {
+		// nothing past the last saved post was recorded, and the cache headers
+		// are only written at the end, so the next run picks the feed up again
+		fmt.Printf("Interrupted while scraping feed '%s'\n", feed.Name)
+		err = s.Db.ReleaseFeedClaim(context.WithoutCancel(ctx), database.ReleaseFeedClaimParams{
+			ID:         feed.ID,
+			ClaimToken: feed.ClaimToken,
+		})
+		if err != nil {
+			fmt.Printf("Error releasing claim on feed '%s': %v\n", feed.Name, err)
+		}
+		summary.Interrupted = 1
+		return summary
+	}
+	// the feed itself is done, so let its bookkeeping finish even when a
+	// shutdown arrives in the meantime
+	ctx = context.WithoutCancel(ctx)
+	var pausedErr *HostPausedError
+	if errors.As(err, &pausedErr) {
+		// no request was made, so this says nothing about the feed itself;
+		// come back once its host is willing to talk again
+		postponeFeed(ctx, s, feed, pausedErr.Until)
+		summary.Paused = 1
+		return summary
+	}
+	recordFeedFetch(ctx, s, feed, result, err)
+	if err != nil {
+		fmt.Printf("Error scraping feed '%s': %v\n", feed.Name, err)
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusGone {
+			deactivateGoneFeed(ctx, s, feed)
+			summary.Gone = 1
+			return summary
+		}
+		recordFeedFailure(ctx, s, feed, err)
+		summary.Failed = 1
+		return summary
+	}
+	hints := storedHints(feed)
+	if result != nil && result.Feed != nil {
+		hints = parsedHints(result.Feed)
+	}
+	success := database.RecordFeedSuccessParams{
+		UpdatedAt: time.Now(),
+		NextFetchAt: sql.NullTime{
+			Time:  scheduleNextFetch(ctx, s, feed, hints),
+			Valid: true,
+		},
+		ID:         feed.ID,
+		ClaimToken: feed.ClaimToken,
+	}
+	err = s.Db.RecordFeedSuccess(ctx, success)
+	if err != nil {
+		fmt.Printf("Error recording success for feed '%s': %v\n", feed.Name, err)
+	}
+	err = trackRedirect(ctx, s, feed, result)
+	if err != nil {
+		fmt.Printf("Error recording redirect for feed '%s': %v\n", feed.Name, err)
+	}
+	return summary
+}
+
+func postponeFeed(ctx context.Context, s *State, feed database.Feed, until time.Time) {
+	err := s.Db.PostponeFeed(ctx, database.PostponeFeedParams{
+		UpdatedAt:   time.Now(),
+		NextFetchAt: sql.NullTime{Time: until, Valid: true},
+		ID:          feed.ID,
+		ClaimToken:  feed.ClaimToken,
+	})
+	if err != nil {
+		fmt.Printf("Error postponing feed '%s': %v\n", feed.Name, err)
+		return
+	}
+	fmt.Printf("Host of feed '%s' asked us to wait, fetching it after %s\n", feed.Name, until.Format(time.RFC3339))
+}
+
+func recordFeedFetch(ctx context.Context, s *State, feed database.Feed, result *FetchResult, fetchErr error) {
+	fetch := database.CreateFeedFetchParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		FeedID:    feed.ID,
+	}
+	var statusErr *StatusError
+	if result != nil {
+		fetch.StatusCode = sql.NullInt32{Int32: int32(result.StatusCode), Valid: true}
+		if result.Feed != nil {
+			fetch.ItemCount = sql.NullInt32{Int32: int32(len(result.Feed.Items)), Valid: true}
+		}
+	} else if errors.As(fetchErr, &statusErr) {
+		fetch.StatusCode = sql.NullInt32{Int32: int32(statusErr.StatusCode), Valid: true}
+	}
+	if fetchErr != nil {
+		fetch.Error = sql.NullString{String: fetchErr.Error(), Valid: true}
+	}
+	err := s.Db.CreateFeedFetch(ctx, fetch)
+	if err != nil {
+		fmt.Printf("Error recording fetch for feed '%s': %v\n", feed.Name, err)
+	}
+}
+
+const (
+	baseFeedBackoff = 5 * time.Minute
+	maxFeedBackoff  = 24 * time.Hour
+)
+
+func feedBackoff(failures int32) time.Duration {
+	backoff := baseFeedBackoff
+	for i := int32(1); i < failures; i++ {
+		backoff *= 2
+		if backoff >= maxFeedBackoff {
+			return maxFeedBackoff
+		}
+	}
+	return backoff
+}
+
+func recordFeedFailure(ctx context.Context, s *State, feed database.Feed, fetchErr error) {
+	backoff := feedBackoff(feed.FailureCount + 1)
+	// never come back sooner than the host asked us to
+	if delay := retryDelay(fetchErr); delay > backoff {
+		backoff = delay
+	}
+	failure := database.RecordFeedFailureParams{
+		UpdatedAt: time.Now(),
+		LastError: sql.NullString{
+			String: fetchErr.Error(),
+			Valid:  true,
+		},
+		BackoffUntil: sql.NullTime{
+			Time:  time.Now().Add(backoff),
+			Valid: true,
+		},
+		ID:         feed.ID,
+		ClaimToken: feed.ClaimToken,
 	}
-	rssfeed, err2 := FetchFeed(context.Background(), feed.Url)
+	err := s.Db.RecordFeedFailure(ctx, failure)
+	if err != nil {
+		fmt.Printf("Error recording failure for feed '%s': %v\n", feed.Name, err)
+		return
+	}
+	fmt.Printf("Feed '%s' has failed %d time(s) in a row, retrying in %v\n", feed.Name, feed.FailureCount+1, backoff)
+}
+
+func scrapeFeed(ctx context.Context, s *State, feed database.Feed) (*FetchResult, ScrapeSummary, error) {
+	var summary ScrapeSummary
+	result, err2 := s.Fetcher.FetchFeed(ctx, feed.Url, feed.Etag.String, feed.LastModified.String)
 	if err2 != nil {
-		return errors.New("error fetching feed")
+		return nil, summary, fmt.Errorf("error fetching feed: %w", err2)
+	}
+	if result.NotModified {
+		fmt.Printf("Feed '%s' has not been modified since the last fetch\n", feed.Name)
+		summary.NotModified = 1
+		return result, summary, nil
+	}
+	legacy, err := legacyPostURLs(ctx, s, feed, result.Feed.Items)
+	if err != nil {
+		if ctx.Err() != nil {
+			return result, summary, ctx.Err()
+		}
+		fmt.Println("error looking up legacy post guids")
 	}
-	for _, item := range rssfeed.Channel.Item {
+	for _, item := range result.Feed.Items {
 		description := sql.NullString{
 			String: item.Description,
 			Valid:  true,
 		}
-		defaultTime := time.Now()
-		publishedTime := defaultTime
-		parsedTime, err := time.Parse(time.RFC1123, item.PubDate)
-		if err == nil {
-			publishedTime = parsedTime
-		} else {
-			parsedTime, err = time.Parse(time.RFC3339, item.PubDate)
-			if err == nil {
-				publishedTime = parsedTime
-			} else {
-				parsedTime, err = time.Parse(time.RFC850, item.PubDate)
-				if err == nil {
-					publishedTime = parsedTime
-				} else {
-					parsedTime, err = time.Parse(time.RFC1123Z, item.PubDate)
-					if err == nil {
-						publishedTime = parsedTime
-					} else {
-						parsedTime, err = time.Parse(time.RFC822Z, item.PubDate)
-						if err == nil {
-							publishedTime = parsedTime
-						} else {
-							fmt.Println("Error parsing publish date:", err)
-						}
-					}
+		publishedTime, err := parsePublishedTime(item.PubDate)
+		if err != nil {
+			fmt.Println("Error parsing publish date:", err)
+			publishedTime = time.Now()
+		}
+		author := sql.NullString{
+			String: item.Author,
+			Valid:  item.Author != "",
+		}
+		hash := contentHash(item)
+		guid := firstNonEmpty(item.ID, item.Link, item.Title, hash)
+		if guid != item.Link && legacy[item.Link] {
+			// adopt the legacy post instead of inserting it a second time
+			err = s.Db.ReplaceLegacyPostGUID(ctx, database.ReplaceLegacyPostGUIDParams{
+				Guid:   guid,
+				FeedID: feed.ID,
+				Url:    item.Link,
+			})
+			if err != nil {
+				if ctx.Err() != nil {
+					return result, summary, ctx.Err()
 				}
+				fmt.Println("error updating legacy post guid")
 			}
 		}
 		post := database.CreatePostParams{
@@ -118,47 +390,112 @@ func ScrapeFeeds(s *State) error {
 			Description: description,
 			PublishedAt: publishedTime,
 			FeedID:      feed.ID,
+			Author:      author,
+			Guid:        guid,
+			ContentHash: hash,
 		}
-		_, err1 := s.Db.CreatePost(context.Background(), post)
+		saved_post, err1 := s.Db.CreatePost(ctx, post)
 		if err1 != nil {
-			if strings.Contains(err1.Error(), "duplicate") || strings.Contains(err1.Error(), "unique constraint") {
+			if errors.Is(err1, sql.ErrNoRows) {
 				continue
+			} else if ctx.Err() != nil {
+				return result, summary, ctx.Err()
 			} else {
 				fmt.Println("error creating post")
 			}
+		} else if saved_post.ID != post.ID {
+			fmt.Printf("Updated post '%s' (revision %d)\n", saved_post.Title, saved_post.Revisions)
+			summary.UpdatedPosts++
+		} else {
+			summary.NewPosts++
 		}
 	}
-	return nil
+	if result.Feed.Link != "" && result.Feed.Link != feed.SiteUrl.String {
+		site := database.UpdateFeedSiteURLParams{
+			UpdatedAt: time.Now(),
+			SiteUrl: sql.NullString{
+				String: result.Feed.Link,
+				Valid:  true,
+			},
+			ID: feed.ID,
+		}
+		err3 := s.Db.UpdateFeedSiteURL(ctx, site)
+		if err3 != nil {
+			return result, summary, errors.New("error updating feed site url")
+		}
+	}
+	if hints := parsedHints(result.Feed); !hints.equal(storedHints(feed)) {
+		err3 := updateScheduleHints(ctx, s, feed, hints)
+		if err3 != nil {
+			return result, summary, errors.New("error updating feed schedule hints")
+		}
+	}
+	if result.ETag != feed.Etag.String || result.LastModified != feed.LastModified.String {
+		headers := database.UpdateFeedCacheHeadersParams{
+			UpdatedAt:    time.Now(),
+			Etag:         sql.NullString{String: result.ETag, Valid: result.ETag != ""},
+			LastModified: sql.NullString{String: result.LastModified, Valid: result.LastModified != ""},
+			ID:           feed.ID,
+		}
+		err3 := s.Db.UpdateFeedCacheHeaders(ctx, headers)
+		if err3 != nil {
+			return result, summary, errors.New("error updating feed cache headers")
+		}
+	}
+	return result, summary, nil
 }
 
-func FetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
-	request, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
+// posts saved before guids were tracked were given their url as guid; look
+// them up once per scrape so the common case costs no query per item
+func legacyPostURLs(ctx context.Context, s *State, feed database.Feed, items []FeedItem) (map[string]bool, error) {
+	var links []string
+	for _, item := range items {
+		if item.Link != "" {
+			links = append(links, item.Link)
+		}
 	}
-	request.Header.Set("User-Agent", "gator")
-	client := &http.Client{}
-	response, err := client.Do(request)
-	if err != nil {
-		return nil, fmt.Errorf("error getting response: %w", err)
+	if len(links) == 0 {
+		return nil, nil
 	}
-	defer response.Body.Close()
-	responseBytes, err := io.ReadAll(response.Body)
+	urls, err := s.Db.GetLegacyPostURLs(ctx, database.GetLegacyPostURLsParams{
+		FeedID: feed.ID,
+		Urls:   links,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("error reading response: %w", err)
+		return nil, err
 	}
-	rssfeed := &RSSFeed{}
-	err = xml.Unmarshal(responseBytes, rssfeed)
-	if err != nil {
-		return nil, fmt.Errorf("error unmarshaling: %w", err)
+	legacy := make(map[string]bool, len(urls))
+	for _, url := range urls {
+		legacy[url] = true
 	}
-	rssfeed.Channel.Title = html.UnescapeString(rssfeed.Channel.Title)
-	rssfeed.Channel.Description = html.UnescapeString(rssfeed.Channel.Description)
-	for i := range rssfeed.Channel.Item {
-		rssfeed.Channel.Item[i].Title = html.UnescapeString(rssfeed.Channel.Item[i].Title)
-		rssfeed.Channel.Item[i].Description = html.UnescapeString(rssfeed.Channel.Item[i].Description)
+	return legacy, nil
+}
+
+func contentHash(item FeedItem) string {
+	sum := sha256.Sum256([]byte(item.Title + "\n" + item.Description))
+	return hex.EncodeToString(sum[:])
+}
+
+var publishedTimeLayouts = []string{
+	time.RFC1123,
+	time.RFC3339,
+	time.RFC850,
+	time.RFC1123Z,
+	time.RFC822Z,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+}
+
+func parsePublishedTime(value string) (time.Time, error) {
+	var err error
+	for _, layout := range publishedTimeLayouts {
+		var parsedTime time.Time
+		parsedTime, err = time.Parse(layout, value)
+		if err == nil {
+			return parsedTime, nil
+		}
 	}
-	return rssfeed, nil
+	return time.Time{}, err
 }
 
 func (c *Commands) Register(name string, f func(*State, Command) error) {
@@ -176,50 +513,335 @@ func (c *Commands) Run(s *State, cmd Command) error {
 func HandlerBrowse(s *State, cmd Command, user database.User) error {
 	var limit int32
 	limit = 2
-	if len(cmd.Args) == 1 {
-		res, err := strconv.ParseInt(cmd.Args[0], 10, 32)
+	unread_only := true
+	limit_set := false
+	for _, arg := range cmd.Args {
+		if arg == "--all" {
+			unread_only = false
+			continue
+		}
+		if limit_set {
+			return errors.New("incorrect number of arguments provided to 'browse' command")
+		}
+		res, err := strconv.ParseInt(arg, 10, 32)
 		if err != nil {
 			return errors.New("argument provided is not an integer")
 		} else {
 			limit = int32(res)
+			limit_set = true
 		}
 	}
 	getposts := database.GetPostsForUserParams{
-		UserID: user.ID,
-		Limit:  limit,
+		UserID:     user.ID,
+		UnreadOnly: unread_only,
+		PostLimit:  limit,
+	}
+	err := printFeedNotices(context.Background(), s, user)
+	if err != nil {
+		return errors.New("error getting feed notices")
 	}
 	posts, err := s.Db.GetPostsForUser(context.Background(), getposts)
 	if err != nil {
 		return errors.New("error getting posts")
 	}
+	table := Table{
+		Columns: []string{"id", "title", "url", "feed", "published_at", "read", "description"},
+		Empty:   "There are no posts to show.",
+	}
+	if unread_only {
+		table.Empty = "You have no unread posts."
+	}
+	for _, post := range posts {
+		table.Rows = append(table.Rows, []any{
+			post.ID,
+			post.Title,
+			post.Url,
+			post.FeedName,
+			post.PublishedAt,
+			post.IsRead,
+			nullString(post.Description),
+		})
+	}
+	return s.Render(table)
+}
+
+func HandlerSearch(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) == 0 {
+		return errors.New("error: no search query provided to 'search' command")
+	}
+	search := database.SearchPostsForUserParams{
+		Query:     strings.Join(cmd.Args, " "),
+		UserID:    user.ID,
+		PostLimit: 10,
+	}
+	posts, err := s.Db.SearchPostsForUser(context.Background(), search)
+	if err != nil {
+		return errors.New("error searching posts")
+	}
+	table := Table{
+		Columns: []string{"id", "title", "url", "feed", "published_at", "relevance"},
+		Empty:   "No posts matched your search.",
+	}
+	for _, post := range posts {
+		table.Rows = append(table.Rows, []any{
+			post.ID,
+			post.Title,
+			post.Url,
+			post.FeedName,
+			post.PublishedAt,
+			math.Round(float64(post.Rank)*1000) / 1000,
+		})
+	}
+	return s.Render(table)
+}
+
+func lookupPost(s *State, arg string) (database.GetPostRow, error) {
+	id, err := uuid.Parse(arg)
+	if err == nil {
+		return s.Db.GetPost(context.Background(), id)
+	}
+	post, err := s.Db.GetPostByURL(context.Background(), arg)
+	return database.GetPostRow(post), err
+}
+
+func HandlerRead(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) != 1 {
+		return errors.New("error: incorrect number of arguments provided to 'read' command")
+	}
+	post, err := lookupPost(s, cmd.Args[0])
+	if err != nil {
+		return errors.New("error getting post from provided id or url")
+	}
+	read := database.MarkPostReadParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		PostID:    post.ID,
+	}
+	err = s.Db.MarkPostRead(context.Background(), read)
+	if err != nil {
+		return errors.New("error marking post as read")
+	}
+	fmt.Printf("Marked '%s' as read\n", post.Title)
+	return nil
+}
+
+func HandlerUnread(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) != 1 {
+		return errors.New("error: incorrect number of arguments provided to 'unread' command")
+	}
+	post, err := lookupPost(s, cmd.Args[0])
+	if err != nil {
+		return errors.New("error getting post from provided id or url")
+	}
+	unread := database.MarkPostUnreadParams{
+		UserID: user.ID,
+		PostID: post.ID,
+	}
+	err = s.Db.MarkPostUnread(context.Background(), unread)
+	if err != nil {
+		return errors.New("error marking post as unread")
+	}
+	fmt.Printf("Marked '%s' as unread\n", post.Title)
+	return nil
+}
+
+func HandlerSave(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) != 1 {
+		return errors.New("error: incorrect number of arguments provided to 'save' command")
+	}
+	post, err := lookupPost(s, cmd.Args[0])
+	if err != nil {
+		return errors.New("error getting post from provided id or url")
+	}
+	save := database.SavePostParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		PostID:    post.ID,
+	}
+	err = s.Db.SavePost(context.Background(), save)
+	if err != nil {
+		return errors.New("error saving post")
+	}
+	fmt.Printf("Saved '%s' for later\n", post.Title)
+	return nil
+}
+
+func HandlerUnsave(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) != 1 {
+		return errors.New("error: incorrect number of arguments provided to 'unsave' command")
+	}
+	post, err := lookupPost(s, cmd.Args[0])
+	if err != nil {
+		return errors.New("error getting post from provided id or url")
+	}
+	unsave := database.UnsavePostParams{
+		UserID: user.ID,
+		PostID: post.ID,
+	}
+	err = s.Db.UnsavePost(context.Background(), unsave)
+	if err != nil {
+		return errors.New("error unsaving post")
+	}
+	fmt.Printf("Removed '%s' from saved posts\n", post.Title)
+	return nil
+}
+
+func HandlerSaved(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) != 0 {
+		return errors.New("error: incorrect number of arguments provided to 'saved' command")
+	}
+	posts, err := s.Db.GetSavedPostsForUser(context.Background(), user.ID)
+	if err != nil {
+		return errors.New("error getting saved posts")
+	}
+	table := Table{
+		Columns: []string{"id", "title", "url", "feed", "saved_at"},
+		Empty:   "You have no saved posts.",
+	}
 	for _, post := range posts {
-		feed_origin, err := s.Db.GetFeedFromID(context.Background(), post.FeedID)
+		table.Rows = append(table.Rows, []any{
+			post.ID,
+			post.Title,
+			post.Url,
+			post.FeedName,
+			post.SavedAt,
+		})
+	}
+	return s.Render(table)
+}
+
+func HandlerMarkAllRead(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) > 1 {
+		return errors.New("error: incorrect number of arguments provided to 'markallread' command")
+	}
+	var marked int64
+	if len(cmd.Args) == 1 {
+		feed, err := s.Db.GetFeed(context.Background(), cmd.Args[0])
+		if err != nil {
+			return errors.New("error getting feed from provided url")
+		}
+		marked, err = s.Db.MarkFeedPostsRead(context.Background(), database.MarkFeedPostsReadParams{
+			CreatedAt: time.Now(),
+			UserID:    user.ID,
+			FeedID:    feed.ID,
+		})
+		if err != nil {
+			return errors.New("error marking feed posts as read")
+		}
+	} else {
+		var err error
+		marked, err = s.Db.MarkAllPostsRead(context.Background(), database.MarkAllPostsReadParams{
+			CreatedAt: time.Now(),
+			UserID:    user.ID,
+		})
 		if err != nil {
-			return err
+			return errors.New("error marking posts as read")
 		}
-		fmt.Printf("Post Title: %s\n", post.Title)
-		fmt.Printf("Post origin feed: %s\n", feed_origin.Name)
-		fmt.Printf("Description: %v\n\n", post.Description.String)
 	}
+	fmt.Printf("Marked %d post(s) as read\n", marked)
 	return nil
 }
 
 func HandlerAgg(s *State, cmd Command) error {
-	if len(cmd.Args) != 1 {
+	if len(cmd.Args) < 1 || len(cmd.Args) > 2 {
 		return errors.New("incorrect amount of arguments provided to the 'agg' command")
 	}
 	time_duration, err := time.ParseDuration(cmd.Args[0])
 	if err != nil {
 		return errors.New("error parsing time duration")
 	}
-	fmt.Printf("Collecting feeds every %v\n", time_duration)
+	concurrency := 1
+	if len(cmd.Args) == 2 {
+		res, err := strconv.Atoi(cmd.Args[1])
+		if err != nil || res < 1 {
+			return errors.New("concurrency must be a positive integer")
+		}
+		concurrency = res
+	}
+	minInterval, maxInterval, err := s.Cfg.FetchIntervals()
+	if err != nil {
+		return err
+	}
+	s.Fetcher, err = NewFetcher(*s.Cfg)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	fmt.Printf("Collecting up to %d due feeds every %v\n", concurrency, time_duration)
+	fmt.Printf("Each feed is refreshed every %v to %v depending on how often it posts\n", minInterval, maxInterval)
+	fmt.Printf("At most %d request(s) at a time per host, %v apart\n", s.Fetcher.settings.hostConcurrency, s.Fetcher.settings.hostInterval)
+	started := time.Now()
+	rounds := 0
+	var total ScrapeSummary
 	ticker := time.NewTicker(time_duration)
-	for ; ; <-ticker.C {
-		err := ScrapeFeeds(s)
+	defer ticker.Stop()
+	for {
+		summary, err := ScrapeFeeds(ctx, s, concurrency)
+		if err != nil && ctx.Err() == nil {
+			fmt.Printf("Error scraping feeds: %v\n", err)
+		}
+		total.Add(summary)
+		rounds++
+		select {
+		case <-ctx.Done():
+			stop()
+			fmt.Printf("\nStopped after %d round(s) in %v\n", rounds, time.Since(started).Round(time.Second))
+			fmt.Printf("Fetched %d feed(s): %d new post(s), %d updated post(s), %d not modified, %d failed, %d paused, %d gone, %d interrupted\n",
+				total.Feeds, total.NewPosts, total.UpdatedPosts, total.NotModified, total.Failed, total.Paused, total.Gone, total.Interrupted)
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func HandlerFeedHealth(s *State, cmd Command) error {
+	if len(cmd.Args) != 0 {
+		return errors.New("error: incorrect number of arguments provided to the 'feedhealth' command")
+	}
+	feeds, err := s.Db.GetFeedHealth(context.Background())
+	if err != nil {
+		return errors.New("error getting feed health")
+	}
+	table := Table{
+		Columns: []string{"name", "url", "last_success", "last_error", "consecutive_failures", "avg_items_per_fetch", "status_history"},
+		Empty:   "There are currently no feeds.",
+	}
+	for _, feed := range feeds {
+		fetches, err := s.Db.GetRecentFeedFetches(context.Background(), database.GetRecentFeedFetchesParams{
+			FeedID: feed.ID,
+			Limit:  10,
+		})
 		if err != nil {
-			return errors.New("error scraping feeds")
+			return errors.New("error getting feed fetch history")
 		}
+		table.Rows = append(table.Rows, []any{
+			feed.Name,
+			feed.Url,
+			nullTime(feed.LastSuccessAt),
+			nullString(feed.LastError),
+			feed.FailureCount,
+			math.Round(feed.AvgItems*10) / 10,
+			statusHistory(fetches),
+		})
 	}
+	return s.Render(table)
+}
+
+func statusHistory(fetches []database.FeedFetch) string {
+	statuses := make([]string, 0, len(fetches))
+	for i := len(fetches) - 1; i >= 0; i-- {
+		if fetches[i].StatusCode.Valid {
+			statuses = append(statuses, strconv.Itoa(int(fetches[i].StatusCode.Int32)))
+		} else {
+			statuses = append(statuses, "ERR")
+		}
+	}
+	return strings.Join(statuses, " ")
 }
 
 func HandlerAddFeed(s *State, cmd Command, user database.User) error {
@@ -261,15 +883,14 @@ func HandlerFeeds(s *State, cmd Command) error {
 	if err != nil {
 		return errors.New("error listing feeds")
 	}
-	if len(feeds) == 0 {
-		fmt.Println("There are currently no feeds.")
-		return nil
-	} else {
-		for _, feed := range feeds {
-			fmt.Printf("Feed Name: %v, URL: %v, Created by: %v\n\n", feed.Name, feed.Url, feed.Name_2.String)
-		}
+	table := Table{
+		Columns: []string{"name", "url", "created_by"},
+		Empty:   "There are currently no feeds.",
 	}
-	return nil
+	for _, feed := range feeds {
+		table.Rows = append(table.Rows, []any{feed.Name, feed.Url, nullString(feed.Name_2)})
+	}
+	return s.Render(table)
 }
 
 func HandlerFollow(s *State, cmd Command, user database.User) error {
@@ -304,15 +925,14 @@ func HandlerFollowing(s *State, cmd Command, user database.User) error {
 	if err != nil {
 		return errors.New("error getting feed-follows for user")
 	}
-	if len(feed_follows) == 0 {
-		fmt.Println("You are currently not following any feeds.")
-		return nil
-	} else {
-		for _, feed_follow := range feed_follows {
-			fmt.Printf("%v\n", feed_follow.FeedName)
-		}
+	table := Table{
+		Columns: []string{"name", "url", "category"},
+		Empty:   "You are currently not following any feeds.",
 	}
-	return nil
+	for _, feed_follow := range feed_follows {
+		table.Rows = append(table.Rows, []any{feed_follow.FeedName, feed_follow.FeedUrl, nullString(feed_follow.Category)})
+	}
+	return s.Render(table)
 }
 
 func MiddlewareLoggedIn(handler func(s *State, cmd Command, user database.User) error) func(*State, Command) error {
@@ -381,19 +1001,14 @@ func HandlerListUsers(s *State, cmd Command) error {
 		return errors.New("error occurred when trying to list all users")
 	}
 	current_user := s.Cfg.User
-	if len(usrs) == 0 {
-		fmt.Println("There are currently no users.")
-		return nil
-	} else {
-		for _, usr := range usrs {
-			if usr == current_user {
-				fmt.Printf("* %s (current)\n", usr)
-			} else {
-				fmt.Printf("* %s\n", usr)
-			}
-		}
+	table := Table{
+		Columns: []string{"name", "current"},
+		Empty:   "There are currently no users.",
 	}
-	return nil
+	for _, usr := range usrs {
+		table.Rows = append(table.Rows, []any{usr, usr == current_user})
+	}
+	return s.Render(table)
 }
 
 func HandlerRegister(s *State, cmd Command) error {
@@ -416,6 +1031,45 @@ func HandlerRegister(s *State, cmd Command) error {
 	return nil
 }
 
+func HandlerSetPassword(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) != 1 {
+		return errors.New("error: usage is 'setpassword <password>'")
+	}
+	password_hash, err := hashPassword(cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("error hashing password: %w", err)
+	}
+	update := database.UpdateUserPasswordParams{
+		ID:           user.ID,
+		UpdatedAt:    time.Now(),
+		PasswordHash: sql.NullString{String: password_hash, Valid: true},
+		FeverApiKey: sql.NullString{
+			String: feverAPIKey(user.Name, cmd.Args[0]),
+			Valid:  true,
+		},
+	}
+	err = s.Db.UpdateUserPassword(context.Background(), update)
+	if err != nil {
+		return fmt.Errorf("error setting password: %w", err)
+	}
+	// a new password signs the user out of every web reader session
+	err = s.Db.DeleteWebSessionsForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("error ending web sessions: %w", err)
+	}
+	// and out of every Google Reader client
+	err = s.Db.DeleteGReaderTokensForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("error ending Google Reader sign-ins: %w", err)
+	}
+	fmt.Printf("Password set for %s\n", user.Name)
+	fmt.Printf("With 'gator web' running, sign in as '%s' from the login page\n", user.Name)
+	fmt.Printf("With 'gator serve' running, sign in as '%s' from:\n", user.Name)
+	fmt.Printf(" * Fever clients at /fever/\n")
+	fmt.Printf(" * Google Reader clients at /greader\n")
+	return nil
+}
+
 func Read() Config {
 	var config_struct Config
 	home_path, err := os.UserHomeDir()
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gabeportillo51/blog_aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestFeedBackoff(t *testing.T) {
+	tests := []struct {
+		failures int32
+		want     time.Duration
+	}{
+		{0, 5 * time.Minute},
+		{1, 5 * time.Minute},
+		{2, 10 * time.Minute},
+		{3, 20 * time.Minute},
+		{6, 160 * time.Minute},
+		{9, 1280 * time.Minute},
+		{10, 24 * time.Hour},
+		{1000, 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := feedBackoff(tt.failures); got != tt.want {
+			t.Errorf("feedBackoff(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestProcessFeedPausedHost(t *testing.T) {
+	db, queries := newFakeDB(t, nil)
+	fetcher := newTestFetcher(t, Config{})
+	until := time.Now().Add(time.Hour)
+	fetcher.limiter("feeds.example").pause(time.Hour)
+	s := &State{Db: queries, Cfg: &Config{}, Fetcher: fetcher}
+	feed := database.Feed{
+		ID:           uuid.New(),
+		Name:         "Paused",
+		Url:          "http://feeds.example/rss",
+		FailureCount: 2,
+		ClaimToken:   uuid.NullUUID{UUID: uuid.New(), Valid: true},
+	}
+
+	summary := processFeed(context.Background(), s, feed)
+	if summary != (ScrapeSummary{Feeds: 1, Paused: 1}) {
+		t.Errorf("processFeed() = %+v, want one paused feed", summary)
+	}
+	// no fetch row, no failure count and no backoff: only the postponement
+	if names := db.names(); !reflect.DeepEqual(names, []string{"PostponeFeed"}) {
+		t.Fatalf("processFeed() ran %v, want only PostponeFeed", names)
+	}
+	args := db.calls[0].args
+	next, ok := args[1].(time.Time)
+	if !ok || next.Before(until.Add(-time.Minute)) || next.After(until.Add(time.Minute)) {
+		t.Errorf("PostponeFeed next_fetch_at = %v, want about %v", args[1], until)
+	}
+	if args[2] != feed.ID.String() || args[3] != feed.ClaimToken.UUID.String() {
+		t.Errorf("PostponeFeed id, claim_token = %v, %v, want %v, %v", args[2], args[3], feed.ID, feed.ClaimToken.UUID)
+	}
+}
+
+func TestScrapeFeedLegacyGUIDs(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprintf(w, `<rss version="2.0"><channel><title>Legacy</title>
+<item><title>One</title><link>%[1]s/one</link><guid>post-1</guid></item>
+<item><title>Two</title><link>%[1]s/two</link><guid>post-2</guid></item>
+<item><description>Only a description</description></item>
+</channel></rss>`, server.URL)
+	}))
+	defer server.Close()
+	db, queries := newFakeDB(t, func(query string, args []driver.Value) [][]driver.Value {
+		if strings.HasPrefix(query, "-- name: GetLegacyPostURLs ") {
+			return [][]driver.Value{{server.URL + "/one"}}
+		}
+		return nil
+	})
+	s := &State{Db: queries, Cfg: &Config{}, Fetcher: newTestFetcher(t, Config{})}
+	feed := database.Feed{ID: uuid.New(), Name: "Legacy", Url: server.URL}
+
+	_, _, err := scrapeFeed(context.Background(), s, feed)
+	if err != nil {
+		t.Fatalf("scrapeFeed() error = %v", err)
+	}
+	var lookups, replaced []fakeCall
+	var guids []driver.Value
+	for _, call := range db.calls {
+		switch {
+		case strings.HasPrefix(call.query, "-- name: GetLegacyPostURLs "):
+			lookups = append(lookups, call)
+		case strings.HasPrefix(call.query, "-- name: ReplaceLegacyPostGUID "):
+			replaced = append(replaced, call)
+		case strings.HasPrefix(call.query, "-- name: CreatePost "):
+			guids = append(guids, call.args[9])
+		}
+	}
+	if len(lookups) != 1 {
+		t.Errorf("GetLegacyPostURLs ran %d times, want once per scrape", len(lookups))
+	}
+	// only the item whose url is still stored as a guid adopts it
+	if len(replaced) != 1 || replaced[0].args[0] != "post-1" || replaced[0].args[2] != server.URL+"/one" {
+		t.Errorf("ReplaceLegacyPostGUID calls = %v, want one for post-1", replaced)
+	}
+	want := []driver.Value{"post-1", "post-2", contentHash(FeedItem{Description: "Only a description"})}
+	if !reflect.DeepEqual(guids, want) {
+		t.Errorf("CreatePost guids = %v, want %v", guids, want)
+	}
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gabeportillo51/blog_aggregator/internal/database"
+)
+
+// fakeDB is a database/sql driver that records every statement it is given
+// and answers queries through a callback, which is enough to drive handlers
+// in tests without a postgres server
+type fakeDB struct {
+	mu    sync.Mutex
+	calls []fakeCall
+	rows  func(query string, args []driver.Value) [][]driver.Value
+}
+
+type fakeCall struct {
+	query string
+	args  []driver.Value
+}
+
+func newFakeDB(t *testing.T, rows func(query string, args []driver.Value) [][]driver.Value) (*fakeDB, *database.Queries) {
+	t.Helper()
+	db := &fakeDB{rows: rows}
+	conn := sql.OpenDB(db)
+	t.Cleanup(func() { conn.Close() })
+	return db, database.New(conn)
+}
+
+// names lists the sqlc query names that ran, in order
+func (db *fakeDB) names() []string {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var names []string
+	for _, call := range db.calls {
+		name, _, _ := strings.Cut(strings.TrimPrefix(call.query, "-- name: "), " ")
+		names = append(names, name)
+	}
+	return names
+}
+
+func (db *fakeDB) record(query string, args []driver.Value) [][]driver.Value {
+	db.mu.Lock()
+	db.calls = append(db.calls, fakeCall{query: query, args: args})
+	db.mu.Unlock()
+	if db.rows == nil {
+		return nil
+	}
+	return db.rows(query, args)
+}
+
+func (db *fakeDB) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{db: db}, nil
+}
+
+func (db *fakeDB) Driver() driver.Driver {
+	return fakeDriver{db: db}
+}
+
+type fakeDriver struct {
+	db *fakeDB
+}
+
+func (d fakeDriver) Open(string) (driver.Conn, error) {
+	return &fakeConn{db: d.db}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{db: c.db, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("fake database does not support transactions")
+}
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.record(s.query, args)
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &fakeRows{rows: s.db.record(s.query, args)}, nil
+}
+
+// fakeRows reports as many columns as the first row has, so callers only
+// need to get the values in the order the generated query scans them
+type fakeRows struct {
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return nil
+	}
+	return make([]string, len(r.rows[0]))
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
@@ -0,0 +1,424 @@
+package config
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+const (
+	defaultHostConcurrency     = 2
+	defaultHostRequestInterval = time.Second
+	defaultRetryAfter          = baseFeedBackoff
+	defaultConnectTimeout      = 10 * time.Second
+	defaultFetchTimeout        = 30 * time.Second
+	defaultMaxFeedSize         = 10 << 20
+	defaultMaxRedirects        = 5
+	defaultUserAgent           = "gator"
+)
+
+type StatusError struct {
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
+
+// HostPausedError is returned without making a request while a host that
+// answered 429 or 503 is still inside the pause it asked for
+type HostPausedError struct {
+	Host  string
+	Until time.Time
+}
+
+func (e *HostPausedError) Error() string {
+	return fmt.Sprintf("host %s asked us to wait until %s", e.Host, e.Until.Format(time.RFC3339))
+}
+
+type FetchResult struct {
+	Feed         *ParsedFeed
+	StatusCode   int
+	NotModified  bool
+	ETag         string
+	LastModified string
+	// RedirectURL is where the feed ended up when every hop on the way was a
+	// permanent redirect
+	RedirectURL string
+}
+
+// Fetcher is shared by every agg worker so that feeds living on the same host
+// take turns instead of hitting it all at once
+type Fetcher struct {
+	client   *http.Client
+	settings fetcherSettings
+	mu       sync.Mutex
+	hosts    map[string]*hostLimiter
+}
+
+type hostLimiter struct {
+	slots       chan struct{}
+	mu          sync.Mutex
+	nextRequest time.Time
+	pausedUntil time.Time
+}
+
+type fetcherSettings struct {
+	hostConcurrency int
+	hostInterval    time.Duration
+	connectTimeout  time.Duration
+	fetchTimeout    time.Duration
+	maxFeedSize     int64
+	maxRedirects    int
+	userAgent       string
+}
+
+func parseSetting(name, value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return fallback, fmt.Errorf("invalid %s '%s'", name, value)
+	}
+	return duration, nil
+}
+
+func (c Config) fetcherSettings() (fetcherSettings, error) {
+	settings := fetcherSettings{
+		hostConcurrency: defaultHostConcurrency,
+		maxFeedSize:     defaultMaxFeedSize,
+		maxRedirects:    defaultMaxRedirects,
+		userAgent:       defaultUserAgent,
+	}
+	var err error
+	settings.hostInterval, err = parseSetting("host_request_interval", c.HostRequestInterval, defaultHostRequestInterval)
+	if err != nil {
+		return settings, err
+	}
+	settings.connectTimeout, err = parseSetting("connect_timeout", c.ConnectTimeout, defaultConnectTimeout)
+	if err != nil {
+		return settings, err
+	}
+	settings.fetchTimeout, err = parseSetting("fetch_timeout", c.FetchTimeout, defaultFetchTimeout)
+	if err != nil {
+		return settings, err
+	}
+	if c.HostConcurrency < 0 {
+		return settings, fmt.Errorf("invalid host_concurrency %d", c.HostConcurrency)
+	} else if c.HostConcurrency > 0 {
+		settings.hostConcurrency = c.HostConcurrency
+	}
+	if c.MaxFeedSize < 0 {
+		return settings, fmt.Errorf("invalid max_feed_size %d", c.MaxFeedSize)
+	} else if c.MaxFeedSize > 0 {
+		settings.maxFeedSize = c.MaxFeedSize
+	}
+	// unset means the default, while 0 turns redirects off altogether
+	if c.MaxRedirects != nil {
+		if *c.MaxRedirects < 0 {
+			return settings, fmt.Errorf("invalid max_redirects %d", *c.MaxRedirects)
+		}
+		settings.maxRedirects = *c.MaxRedirects
+	}
+	if c.UserAgent != "" {
+		settings.userAgent = c.UserAgent
+	}
+	return settings, nil
+}
+
+func NewFetcher(cfg Config) (*Fetcher, error) {
+	settings, err := cfg.fetcherSettings()
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: settings.connectTimeout}
+	f := &Fetcher{
+		client: &http.Client{
+			Transport: &http.Transport{
+				Proxy:                 http.ProxyFromEnvironment,
+				DialContext:           dialer.DialContext,
+				TLSHandshakeTimeout:   settings.connectTimeout,
+				MaxConnsPerHost:       settings.hostConcurrency,
+				IdleConnTimeout:       90 * time.Second,
+				DisableCompression:    true,
+				ResponseHeaderTimeout: settings.fetchTimeout,
+			},
+			// covers the whole exchange, so a server trickling the body
+			// can't hold a worker forever
+			Timeout: settings.fetchTimeout,
+		},
+		settings: settings,
+		hosts:    make(map[string]*hostLimiter),
+	}
+	f.client.CheckRedirect = f.checkRedirect
+	return f, nil
+}
+
+type fetchSlotKey struct{}
+
+// fetchSlot is the host slot a fetch holds. A redirect to another host trades
+// it for a slot on that host, so the new host's limits and pauses apply too;
+// only one slot is held at a time so two feeds redirecting to each other's
+// hosts can't deadlock
+type fetchSlot struct {
+	host    string
+	limiter *hostLimiter
+}
+
+func (f *Fetcher) hold(ctx context.Context, slot *fetchSlot, host string) error {
+	if slot.limiter != nil {
+		if slot.host == host {
+			return nil
+		}
+		slot.release()
+	}
+	limiter := f.limiter(host)
+	err := f.acquire(ctx, host, limiter)
+	if err != nil {
+		return err
+	}
+	slot.host, slot.limiter = host, limiter
+	return nil
+}
+
+func (slot *fetchSlot) release() {
+	if slot.limiter != nil {
+		<-slot.limiter.slots
+		slot.limiter = nil
+	}
+}
+
+func (f *Fetcher) checkRedirect(request *http.Request, via []*http.Request) error {
+	if f.settings.maxRedirects == 0 {
+		return errors.New("not following redirect, max_redirects is 0")
+	}
+	if len(via) > f.settings.maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", f.settings.maxRedirects)
+	}
+	slot, ok := request.Context().Value(fetchSlotKey{}).(*fetchSlot)
+	if !ok {
+		return nil
+	}
+	return f.hold(request.Context(), slot, strings.ToLower(request.URL.Hostname()))
+}
+
+func (f *Fetcher) limiter(host string) *hostLimiter {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	limiter, ok := f.hosts[host]
+	if !ok {
+		limiter = &hostLimiter{slots: make(chan struct{}, f.settings.hostConcurrency)}
+		f.hosts[host] = limiter
+	}
+	return limiter
+}
+
+// acquire waits for a free slot on the host and for its turn in the request
+// schedule; the caller must release the slot once the response is read
+func (f *Fetcher) acquire(ctx context.Context, host string, limiter *hostLimiter) error {
+	select {
+	case limiter.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	now := time.Now()
+	limiter.mu.Lock()
+	if limiter.pausedUntil.After(now) {
+		until := limiter.pausedUntil
+		limiter.mu.Unlock()
+		<-limiter.slots
+		return &HostPausedError{Host: host, Until: until}
+	}
+	start := now
+	if limiter.nextRequest.After(start) {
+		start = limiter.nextRequest
+	}
+	limiter.nextRequest = start.Add(f.settings.hostInterval)
+	limiter.mu.Unlock()
+	if wait := start.Sub(now); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			<-limiter.slots
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (limiter *hostLimiter) pause(retryAfter time.Duration) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	until := time.Now().Add(retryAfter)
+	if until.After(limiter.pausedUntil) {
+		limiter.pausedUntil = until
+	}
+}
+
+// Retry-After is either a number of seconds or an http date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return defaultRetryAfter
+	}
+	var retryAfter time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		retryAfter = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		retryAfter = date.Sub(now)
+	} else {
+		return defaultRetryAfter
+	}
+	return min(max(retryAfter, time.Second), maxFeedBackoff)
+}
+
+func (f *Fetcher) FetchFeed(ctx context.Context, feedURL, etag, lastModified string) (*FetchResult, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	request.Header.Set("User-Agent", f.settings.userAgent)
+	// decoded by hand so the size limit applies to the decompressed feed
+	request.Header.Set("Accept-Encoding", "gzip, deflate, br")
+	if etag != "" {
+		request.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		request.Header.Set("If-Modified-Since", lastModified)
+	}
+	slot := &fetchSlot{}
+	err = f.hold(ctx, slot, strings.ToLower(request.URL.Hostname()))
+	if err != nil {
+		return nil, err
+	}
+	defer slot.release()
+	response, err := f.client.Do(request.WithContext(context.WithValue(ctx, fetchSlotKey{}, slot)))
+	if err != nil {
+		return nil, fmt.Errorf("error getting response: %w", err)
+	}
+	defer response.Body.Close()
+	result := &FetchResult{
+		StatusCode:   response.StatusCode,
+		ETag:         etag,
+		LastModified: lastModified,
+	}
+	if final := response.Request.URL.String(); final != feedURL && permanentRedirect(response) {
+		result.RedirectURL = final
+	}
+	if value := response.Header.Get("ETag"); value != "" {
+		result.ETag = value
+	}
+	if value := response.Header.Get("Last-Modified"); value != "" {
+		result.LastModified = value
+	}
+	if response.StatusCode == http.StatusNotModified {
+		result.NotModified = true
+		return result, nil
+	}
+	if response.StatusCode == http.StatusTooManyRequests || response.StatusCode == http.StatusServiceUnavailable {
+		retryAfter := parseRetryAfter(response.Header.Get("Retry-After"), time.Now())
+		// the slot is on the host that answered, wherever the feed started
+		slot.limiter.pause(retryAfter)
+		return nil, &StatusError{StatusCode: response.StatusCode, RetryAfter: retryAfter}
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, &StatusError{StatusCode: response.StatusCode}
+	}
+	responseBytes, err := f.readBody(response)
+	if err != nil {
+		return nil, err
+	}
+	result.Feed, err = parseFeed(response.Header.Get("Content-Type"), responseBytes)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// permanentRedirect needs at least one hop: a url that only changed because
+// String() re-encoded it was never redirected
+func permanentRedirect(response *http.Response) bool {
+	if response.Request.Response == nil {
+		return false
+	}
+	for request := response.Request; request.Response != nil; request = request.Response.Request {
+		code := request.Response.StatusCode
+		if code != http.StatusMovedPermanently && code != http.StatusPermanentRedirect {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *Fetcher) readBody(response *http.Response) ([]byte, error) {
+	var body io.Reader = response.Body
+	switch encoding := strings.ToLower(strings.TrimSpace(response.Header.Get("Content-Encoding"))); encoding {
+	case "", "identity":
+	case "gzip", "x-gzip":
+		reader, err := gzip.NewReader(response.Body)
+		if err != nil {
+			return nil, fmt.Errorf("error reading gzip response: %w", err)
+		}
+		defer reader.Close()
+		body = reader
+	case "deflate":
+		// deflate is meant to be zlib wrapped, but plenty of servers send
+		// the raw stream instead
+		buffered := bufio.NewReader(response.Body)
+		header, err := buffered.Peek(2)
+		if err == nil && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 && header[0]&0x0f == 8 {
+			reader, err := zlib.NewReader(buffered)
+			if err != nil {
+				return nil, fmt.Errorf("error reading deflate response: %w", err)
+			}
+			defer reader.Close()
+			body = reader
+		} else {
+			reader := flate.NewReader(buffered)
+			defer reader.Close()
+			body = reader
+		}
+	case "br":
+		body = brotli.NewReader(response.Body)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding '%s'", encoding)
+	}
+	data, err := io.ReadAll(io.LimitReader(body, f.settings.maxFeedSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+	if int64(len(data)) > f.settings.maxFeedSize {
+		return nil, fmt.Errorf("feed is larger than %d bytes", f.settings.maxFeedSize)
+	}
+	return data, nil
+}
+
+// retryDelay is how long the server asked us to stay away, if it said so
+func retryDelay(err error) time.Duration {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.RetryAfter
+	}
+	var pausedErr *HostPausedError
+	if errors.As(err, &pausedErr) {
+		return time.Until(pausedErr.Until)
+	}
+	return 0
+}
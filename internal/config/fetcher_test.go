@@ -0,0 +1,459 @@
+package config
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+const testFeed = `<rss version="2.0"><channel><title>Test</title><item><title>Post</title><link>https://example.com/1</link></item></channel></rss>`
+
+func newTestFetcher(t *testing.T, cfg Config) *Fetcher {
+	t.Helper()
+	if cfg.HostRequestInterval == "" {
+		cfg.HostRequestInterval = "0s"
+	}
+	fetcher, err := NewFetcher(cfg)
+	if err != nil {
+		t.Fatalf("NewFetcher() error = %v", err)
+	}
+	return fetcher
+}
+
+func maxRedirects(n int) *int {
+	return &n
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{"empty", "", 5 * time.Minute},
+		{"seconds", "120", 2 * time.Minute},
+		{"padded seconds", " 30 ", 30 * time.Second},
+		{"zero seconds", "0", time.Second},
+		{"negative seconds", "-10", time.Second},
+		{"more than a day", "604800", 24 * time.Hour},
+		{"http date", "Mon, 01 Jan 2024 12:10:00 GMT", 10 * time.Minute},
+		{"http date in the past", "Mon, 01 Jan 2024 11:00:00 GMT", time.Second},
+		{"garbage", "soon", 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.value, now); got != tt.want {
+				t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetcherSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"defaults", Config{}, false},
+		{"custom", Config{HostConcurrency: 4, HostRequestInterval: "250ms", FetchTimeout: "1m", MaxFeedSize: 1024, MaxRedirects: maxRedirects(2)}, false},
+		{"redirects off", Config{MaxRedirects: maxRedirects(0)}, false},
+		{"bad interval", Config{HostRequestInterval: "often"}, true},
+		{"negative timeout", Config{FetchTimeout: "-1s"}, true},
+		{"negative concurrency", Config{HostConcurrency: -1}, true},
+		{"negative size", Config{MaxFeedSize: -1}, true},
+		{"negative redirects", Config{MaxRedirects: maxRedirects(-1)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFetcher(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewFetcher() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFetchFeedPausesHostAfterTooManyRequests(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	fetcher := newTestFetcher(t, Config{})
+
+	_, err := fetcher.FetchFeed(context.Background(), server.URL+"/a.xml", "", "")
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("FetchFeed() error = %v, want a 429 StatusError", err)
+	}
+	if got := retryDelay(err); got != 2*time.Minute {
+		t.Errorf("retryDelay() = %v, want %v", got, 2*time.Minute)
+	}
+
+	// a different feed on the same host must wait out the pause too
+	_, err = fetcher.FetchFeed(context.Background(), server.URL+"/b.xml", "", "")
+	var pausedErr *HostPausedError
+	if !errors.As(err, &pausedErr) {
+		t.Fatalf("FetchFeed() error = %v, want a HostPausedError", err)
+	}
+	if got := retryDelay(err); got <= time.Minute || got > 2*time.Minute {
+		t.Errorf("retryDelay() = %v, want about %v", got, 2*time.Minute)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("server saw %d requests, want 1", got)
+	}
+}
+
+func TestFetchFeedSpacesRequestsToTheSameHost(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, testFeed)
+	}))
+	defer server.Close()
+	interval := 100 * time.Millisecond
+	fetcher := newTestFetcher(t, Config{HostRequestInterval: interval.String()})
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := fetcher.FetchFeed(context.Background(), server.URL, "", ""); err != nil {
+			t.Fatalf("FetchFeed() error = %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 2*interval {
+		t.Errorf("three fetches took %v, want at least %v", elapsed, 2*interval)
+	}
+}
+
+func TestFetchFeedConditionalRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Mon, 01 Jan 2024 00:00:00 GMT")
+		fmt.Fprint(w, testFeed)
+	}))
+	defer server.Close()
+	fetcher := newTestFetcher(t, Config{})
+
+	result, err := fetcher.FetchFeed(context.Background(), server.URL, "", "")
+	if err != nil {
+		t.Fatalf("FetchFeed() error = %v", err)
+	}
+	if result.NotModified || result.ETag != `"v1"` || result.LastModified != "Mon, 01 Jan 2024 00:00:00 GMT" {
+		t.Errorf("FetchFeed() = %+v, want a fresh result with validators", result)
+	}
+	if result.Feed == nil || len(result.Feed.Items) != 1 {
+		t.Fatalf("FetchFeed() feed = %+v, want one item", result.Feed)
+	}
+
+	result, err = fetcher.FetchFeed(context.Background(), server.URL, result.ETag, result.LastModified)
+	if err != nil {
+		t.Fatalf("FetchFeed() error = %v", err)
+	}
+	if !result.NotModified || result.Feed != nil || result.ETag != `"v1"` {
+		t.Errorf("FetchFeed() = %+v, want a not modified result", result)
+	}
+}
+
+func compress(t *testing.T, encoding string, data []byte) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	var writer interface {
+		Write([]byte) (int, error)
+		Close() error
+	}
+	switch encoding {
+	case "gzip":
+		writer = gzip.NewWriter(&buffer)
+	case "zlib":
+		writer = zlib.NewWriter(&buffer)
+	case "flate":
+		writer, _ = flate.NewWriter(&buffer, flate.DefaultCompression)
+	case "br":
+		writer = brotli.NewWriter(&buffer)
+	default:
+		return data
+	}
+	if _, err := writer.Write(data); err != nil {
+		t.Fatalf("compressing %s: %v", encoding, err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("compressing %s: %v", encoding, err)
+	}
+	return buffer.Bytes()
+}
+
+func TestFetchFeedReadBody(t *testing.T) {
+	feed := []byte(testFeed)
+	// well under the cap on the wire, far over it once decoded
+	padded := []byte(strings.Replace(testFeed, "<title>Test</title>", "<title>Test</title><!--"+strings.Repeat("x", 4096)+"-->", 1))
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		maxSize  int64
+		wantErr  string
+	}{
+		{name: "identity", encoding: "", body: feed},
+		{name: "explicit identity", encoding: "identity", body: feed},
+		{name: "gzip", encoding: "gzip", body: compress(t, "gzip", feed)},
+		{name: "x-gzip", encoding: "x-gzip", body: compress(t, "gzip", feed)},
+		{name: "zlib deflate", encoding: "deflate", body: compress(t, "zlib", feed)},
+		{name: "raw deflate", encoding: "deflate", body: compress(t, "flate", feed)},
+		{name: "brotli", encoding: "br", body: compress(t, "br", feed)},
+		{name: "mixed case", encoding: " GZip ", body: compress(t, "gzip", feed)},
+		{name: "exactly the cap", encoding: "", body: feed, maxSize: int64(len(feed))},
+		{name: "over the cap", encoding: "", body: feed, maxSize: int64(len(feed)) - 1, wantErr: "larger than"},
+		{name: "gzip bomb", encoding: "gzip", body: compress(t, "gzip", padded), maxSize: 1024, wantErr: "larger than"},
+		{name: "brotli bomb", encoding: "br", body: compress(t, "br", padded), maxSize: 1024, wantErr: "larger than"},
+		{name: "corrupt gzip", encoding: "gzip", body: feed, wantErr: "gzip"},
+		{name: "unsupported", encoding: "zstd", body: feed, wantErr: "unsupported content encoding"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.encoding != "" {
+					w.Header().Set("Content-Encoding", tt.encoding)
+				}
+				w.Header().Set("Content-Type", "application/rss+xml")
+				w.Write(tt.body)
+			}))
+			defer server.Close()
+			fetcher := newTestFetcher(t, Config{MaxFeedSize: tt.maxSize})
+
+			result, err := fetcher.FetchFeed(context.Background(), server.URL, "", "")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("FetchFeed() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FetchFeed() error = %v", err)
+			}
+			if result.Feed.Title != "Test" || len(result.Feed.Items) != 1 {
+				t.Errorf("FetchFeed() feed = %+v, want the test feed", result.Feed)
+			}
+		})
+	}
+}
+
+func TestFetchFeedRequest(t *testing.T) {
+	var userAgent, acceptEncoding string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+		acceptEncoding = r.Header.Get("Accept-Encoding")
+		fmt.Fprint(w, testFeed)
+	}))
+	defer server.Close()
+	fetcher := newTestFetcher(t, Config{UserAgent: "gator-test/1.0"})
+
+	if _, err := fetcher.FetchFeed(context.Background(), server.URL, "", ""); err != nil {
+		t.Fatalf("FetchFeed() error = %v", err)
+	}
+	if userAgent != "gator-test/1.0" {
+		t.Errorf("User-Agent = %q, want %q", userAgent, "gator-test/1.0")
+	}
+	if acceptEncoding != "gzip, deflate, br" {
+		t.Errorf("Accept-Encoding = %q, want %q", acceptEncoding, "gzip, deflate, br")
+	}
+}
+
+func TestFetchFeedRedirectLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		hops      int
+		redirects *int
+		wantErr   bool
+	}{
+		{"within the limit", 2, maxRedirects(2), false},
+		{"past the limit", 3, maxRedirects(2), true},
+		{"default limit", defaultMaxRedirects, nil, false},
+		{"past the default limit", defaultMaxRedirects + 1, nil, true},
+		{"redirects off", 1, maxRedirects(0), true},
+		{"redirects off without a redirect", 0, maxRedirects(0), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var hop int
+				fmt.Sscanf(r.URL.Path, "/%d", &hop)
+				if hop < tt.hops {
+					http.Redirect(w, r, fmt.Sprintf("/%d", hop+1), http.StatusFound)
+					return
+				}
+				fmt.Fprint(w, testFeed)
+			}))
+			defer server.Close()
+			fetcher := newTestFetcher(t, Config{MaxRedirects: tt.redirects})
+
+			_, err := fetcher.FetchFeed(context.Background(), server.URL+"/0", "", "")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("FetchFeed() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// crossHostServers serves the feed from target, reached from origin through a
+// redirect that names it as localhost rather than 127.0.0.1, so the two count
+// as different hosts
+func crossHostServers(t *testing.T, target http.HandlerFunc) (origin string) {
+	t.Helper()
+	targetServer := httptest.NewServer(target)
+	t.Cleanup(targetServer.Close)
+	location := strings.Replace(targetServer.URL, "127.0.0.1", "localhost", 1) + "/feed"
+	originServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, location, http.StatusFound)
+	}))
+	t.Cleanup(originServer.Close)
+	return originServer.URL + "/feed"
+}
+
+func TestFetchFeedRedirectWaitsForPausedHost(t *testing.T) {
+	var requests atomic.Int32
+	origin := crossHostServers(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		fmt.Fprint(w, testFeed)
+	})
+	fetcher := newTestFetcher(t, Config{})
+	fetcher.limiter("localhost").pause(time.Hour)
+
+	_, err := fetcher.FetchFeed(context.Background(), origin, "", "")
+	var pausedErr *HostPausedError
+	if !errors.As(err, &pausedErr) || pausedErr.Host != "localhost" {
+		t.Fatalf("FetchFeed() error = %v, want a HostPausedError for localhost", err)
+	}
+	if got := requests.Load(); got != 0 {
+		t.Errorf("paused host saw %d requests, want 0", got)
+	}
+	// the slot on the origin was handed back, not leaked
+	if got := len(fetcher.limiter("127.0.0.1").slots); got != 0 {
+		t.Errorf("origin host has %d slots taken, want 0", got)
+	}
+}
+
+func TestFetchFeedRedirectPausesTheHostThatAnswered(t *testing.T) {
+	origin := crossHostServers(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	fetcher := newTestFetcher(t, Config{})
+
+	_, err := fetcher.FetchFeed(context.Background(), origin, "", "")
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("FetchFeed() error = %v, want a 503 StatusError", err)
+	}
+	now := time.Now()
+	if until := fetcher.limiter("localhost").pausedUntil; until.Before(now.Add(time.Minute)) {
+		t.Errorf("localhost paused until %v, want about two minutes from now", until)
+	}
+	if until := fetcher.limiter("127.0.0.1").pausedUntil; until.After(now) {
+		t.Errorf("origin host paused until %v, want it left alone", until)
+	}
+	for _, host := range []string{"localhost", "127.0.0.1"} {
+		if got := len(fetcher.limiter(host).slots); got != 0 {
+			t.Errorf("%s has %d slots taken, want 0", host, got)
+		}
+	}
+}
+
+func TestFetchFeedRedirectSpacesRequestsToTheNewHost(t *testing.T) {
+	origin := crossHostServers(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testFeed)
+	})
+	interval := 100 * time.Millisecond
+	fetcher := newTestFetcher(t, Config{HostRequestInterval: interval.String()})
+
+	// each fetch asks both hosts, and localhost's turns have to be spaced too
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := fetcher.FetchFeed(context.Background(), origin, "", ""); err != nil {
+			t.Fatalf("FetchFeed() error = %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 2*interval {
+		t.Errorf("three fetches took %v, want at least %v", elapsed, 2*interval)
+	}
+	if next := fetcher.limiter("localhost").nextRequest; next.IsZero() {
+		t.Errorf("localhost was never scheduled, want the redirect to take a turn")
+	}
+}
+
+func TestFetchFeedTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+	fetcher := newTestFetcher(t, Config{FetchTimeout: "50ms"})
+
+	if _, err := fetcher.FetchFeed(context.Background(), server.URL, "", ""); err == nil {
+		t.Errorf("FetchFeed() error = nil, want a timeout")
+	}
+}
+
+func TestFetchFeedRedirectURL(t *testing.T) {
+	tests := []struct {
+		name  string
+		path  string
+		codes []int
+		want  string
+	}{
+		{"no redirect", "/feed", nil, ""},
+		{"moved permanently", "/old", []int{http.StatusMovedPermanently}, "/new"},
+		{"permanent chain", "/old", []int{http.StatusPermanentRedirect, http.StatusMovedPermanently}, "/new"},
+		{"temporary hop", "/old", []int{http.StatusMovedPermanently, http.StatusFound}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var hop int
+				fmt.Sscanf(r.URL.Query().Get("hop"), "%d", &hop)
+				if r.URL.Path == "/old" && hop < len(tt.codes) {
+					target := fmt.Sprintf("/old?hop=%d", hop+1)
+					if hop == len(tt.codes)-1 {
+						target = "/new"
+					}
+					http.Redirect(w, r, target, tt.codes[hop])
+					return
+				}
+				fmt.Fprint(w, testFeed)
+			}))
+			defer server.Close()
+			fetcher := newTestFetcher(t, Config{})
+
+			result, err := fetcher.FetchFeed(context.Background(), server.URL+tt.path, "", "")
+			if err != nil {
+				t.Fatalf("FetchFeed() error = %v", err)
+			}
+			want := ""
+			if tt.want != "" {
+				want = server.URL + tt.want
+			}
+			if result.RedirectURL != want {
+				t.Errorf("FetchFeed() RedirectURL = %q, want %q", result.RedirectURL, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,327 @@
+package config
+
+import (
+	"crypto/md5"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"hash/crc32"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gabeportillo51/blog_aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	feverAPIVersion = 3
+	feverItemLimit  = 50
+)
+
+type feverGroup struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+}
+
+type feverFeedsGroup struct {
+	GroupID int64  `json:"group_id"`
+	FeedIDs string `json:"feed_ids"`
+}
+
+type feverFeed struct {
+	ID                int64  `json:"id"`
+	FaviconID         int64  `json:"favicon_id"`
+	Title             string `json:"title"`
+	Url               string `json:"url"`
+	SiteUrl           string `json:"site_url"`
+	IsSpark           int    `json:"is_spark"`
+	LastUpdatedOnTime int64  `json:"last_updated_on_time"`
+}
+
+type feverItem struct {
+	ID            int64  `json:"id"`
+	FeedID        int64  `json:"feed_id"`
+	Title         string `json:"title"`
+	Author        string `json:"author"`
+	HTML          string `json:"html"`
+	Url           string `json:"url"`
+	IsSaved       int    `json:"is_saved"`
+	IsRead        int    `json:"is_read"`
+	CreatedOnTime int64  `json:"created_on_time"`
+}
+
+// fever clients authenticate with md5("email:password"); gator has no
+// emails, so the user name takes its place
+func feverAPIKey(name, password string) string {
+	sum := md5.Sum([]byte(name + ":" + password))
+	return hex.EncodeToString(sum[:])
+}
+
+// categories have no ids of their own, so derive a stable one from the name
+func feverGroupID(category string) int64 {
+	return int64(crc32.ChecksumIEEE([]byte(category)) & 0x7fffffff)
+}
+
+func feverBool(value bool) int {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+func joinIDs(ids []int64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+func parseIDs(value string) []int64 {
+	var ids []int64
+	for _, part := range strings.Split(value, ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+		if err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func formInt64(r *http.Request, key string) sql.NullInt64 {
+	id, err := strconv.ParseInt(r.FormValue(key), 10, 64)
+	if err != nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: id, Valid: true}
+}
+
+func (a *apiServer) handleFever(w http.ResponseWriter, r *http.Request) {
+	response := map[string]interface{}{
+		"api_version": feverAPIVersion,
+		"auth":        0,
+	}
+	if _, ok := r.URL.Query()["api"]; !ok {
+		respondWithJSON(w, http.StatusOK, response)
+		return
+	}
+	api_key := strings.ToLower(strings.TrimSpace(r.FormValue("api_key")))
+	if api_key == "" {
+		respondWithJSON(w, http.StatusOK, response)
+		return
+	}
+	user, err := a.state.Db.GetUserByFeverAPIKey(r.Context(), sql.NullString{String: api_key, Valid: true})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithJSON(w, http.StatusOK, response)
+		return
+	} else if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	response["auth"] = 1
+
+	feeds, err := a.state.Db.GetSubscriptionsForUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "database error")
+		return
+	}
+	var last_refreshed int64
+	for _, feed := range feeds {
+		if feed.LastSuccessAt.Valid && feed.LastSuccessAt.Time.Unix() > last_refreshed {
+			last_refreshed = feed.LastSuccessAt.Time.Unix()
+		}
+	}
+	response["last_refreshed_on_time"] = last_refreshed
+
+	if r.FormValue("mark") != "" {
+		err = a.feverMark(r, user, feeds)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+	}
+
+	query := r.URL.Query()
+	if _, ok := query["groups"]; ok {
+		response["groups"], response["feeds_groups"] = feverGroups(feeds)
+	}
+	if _, ok := query["feeds"]; ok {
+		items := make([]feverFeed, 0, len(feeds))
+		for _, feed := range feeds {
+			item := feverFeed{
+				ID:      feed.NumericID,
+				Title:   feed.Name,
+				Url:     feed.Url,
+				SiteUrl: feed.SiteUrl.String,
+			}
+			if feed.LastSuccessAt.Valid {
+				item.LastUpdatedOnTime = feed.LastSuccessAt.Time.Unix()
+			}
+			items = append(items, item)
+		}
+		response["feeds"] = items
+		_, response["feeds_groups"] = feverGroups(feeds)
+	}
+	if _, ok := query["favicons"]; ok {
+		response["favicons"] = []struct{}{}
+	}
+	if _, ok := query["links"]; ok {
+		response["links"] = []struct{}{}
+	}
+	if _, ok := query["items"]; ok {
+		params := database.GetFeverItemsForUserParams{
+			UserID:    user.ID,
+			SinceID:   formInt64(r, "since_id"),
+			MaxID:     formInt64(r, "max_id"),
+			ItemLimit: feverItemLimit,
+		}
+		if with_ids := r.FormValue("with_ids"); with_ids != "" {
+			params.WithIds = parseIDs(with_ids)
+		}
+		posts, err := a.state.Db.GetFeverItemsForUser(r.Context(), params)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		total, err := a.state.Db.CountPostsForUser(r.Context(), user.ID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		items := make([]feverItem, 0, len(posts))
+		for _, post := range posts {
+			items = append(items, feverItem{
+				ID:            post.NumericID,
+				FeedID:        post.FeedNumericID,
+				Title:         post.Title,
+				Author:        post.Author.String,
+				HTML:          post.Description.String,
+				Url:           post.Url,
+				IsSaved:       feverBool(post.IsSaved),
+				IsRead:        feverBool(post.IsRead),
+				CreatedOnTime: post.PublishedAt.Unix(),
+			})
+		}
+		response["items"] = items
+		response["total_items"] = total
+	}
+	if _, ok := query["unread_item_ids"]; ok {
+		ids, err := a.state.Db.GetUnreadPostIDsForUser(r.Context(), user.ID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		response["unread_item_ids"] = joinIDs(ids)
+	}
+	if _, ok := query["saved_item_ids"]; ok {
+		ids, err := a.state.Db.GetSavedPostIDsForUser(r.Context(), user.ID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		response["saved_item_ids"] = joinIDs(ids)
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func feverGroups(feeds []database.GetSubscriptionsForUserRow) ([]feverGroup, []feverFeedsGroup) {
+	groups := []feverGroup{}
+	feeds_groups := []feverFeedsGroup{}
+	members := make(map[int64][]int64)
+	for _, feed := range feeds {
+		if !feed.Category.Valid || feed.Category.String == "" {
+			continue
+		}
+		id := feverGroupID(feed.Category.String)
+		if _, ok := members[id]; !ok {
+			groups = append(groups, feverGroup{ID: id, Title: feed.Category.String})
+		}
+		members[id] = append(members[id], feed.NumericID)
+	}
+	for _, group := range groups {
+		feeds_groups = append(feeds_groups, feverFeedsGroup{
+			GroupID: group.ID,
+			FeedIDs: joinIDs(members[group.ID]),
+		})
+	}
+	return groups, feeds_groups
+}
+
+func (a *apiServer) feverMark(r *http.Request, user database.User, feeds []database.GetSubscriptionsForUserRow) error {
+	id := formInt64(r, "id")
+	if !id.Valid {
+		return nil
+	}
+	as := r.FormValue("as")
+	switch r.FormValue("mark") {
+	case "item":
+		post, err := a.state.Db.GetPostByNumericID(r.Context(), id.Int64)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		switch as {
+		case "read":
+			return a.state.Db.MarkPostRead(r.Context(), database.MarkPostReadParams{
+				ID:        uuid.New(),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+				UserID:    user.ID,
+				PostID:    post.ID,
+			})
+		case "unread":
+			return a.state.Db.MarkPostUnread(r.Context(), database.MarkPostUnreadParams{
+				UserID: user.ID,
+				PostID: post.ID,
+			})
+		case "saved":
+			return a.state.Db.SavePost(r.Context(), database.SavePostParams{
+				ID:        uuid.New(),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+				UserID:    user.ID,
+				PostID:    post.ID,
+			})
+		case "unsaved":
+			return a.state.Db.UnsavePost(r.Context(), database.UnsavePostParams{
+				UserID: user.ID,
+				PostID: post.ID,
+			})
+		}
+		return nil
+	case "feed", "group":
+		if as != "read" {
+			return nil
+		}
+		// only mark posts the client had already seen when it sent the request
+		before := time.Now()
+		if value := formInt64(r, "before"); value.Valid {
+			before = time.Unix(value.Int64, 0)
+		}
+		params := database.MarkPostsReadBeforeParams{
+			CreatedAt: time.Now(),
+			UserID:    user.ID,
+			Before:    before,
+		}
+		if r.FormValue("mark") == "feed" {
+			params.FeedNumericID = id
+		} else if id.Int64 != 0 {
+			// group 0 is fever's "Kindling" group of every feed
+			for _, feed := range feeds {
+				if feed.Category.Valid && feverGroupID(feed.Category.String) == id.Int64 {
+					params.Category = feed.Category
+					break
+				}
+			}
+			if !params.Category.Valid {
+				return nil
+			}
+		}
+		_, err := a.state.Db.MarkPostsReadBefore(r.Context(), params)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,577 @@
+package config
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gabeportillo51/blog_aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	greaderItemPrefix   = "tag:google.com,2005:reader/item/"
+	greaderReadingList  = "user/-/state/com.google/reading-list"
+	greaderRead         = "user/-/state/com.google/read"
+	greaderStarred      = "user/-/state/com.google/starred"
+	greaderLabelPrefix  = "user/-/label/"
+	greaderFeedPrefix   = "feed/"
+	greaderDefaultItems = 20
+	greaderMaxItems     = 10000
+)
+
+type greaderSubscription struct {
+	ID         string            `json:"id"`
+	Title      string            `json:"title"`
+	Categories []greaderCategory `json:"categories"`
+	Url        string            `json:"url"`
+	HtmlUrl    string            `json:"htmlUrl"`
+	IconUrl    string            `json:"iconUrl"`
+}
+
+type greaderCategory struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+type greaderTag struct {
+	ID   string `json:"id"`
+	Type string `json:"type,omitempty"`
+}
+
+type greaderUnreadCount struct {
+	ID                      string `json:"id"`
+	Count                   int64  `json:"count"`
+	NewestItemTimestampUsec string `json:"newestItemTimestampUsec"`
+}
+
+type greaderItemRef struct {
+	ID              string   `json:"id"`
+	DirectStreamIDs []string `json:"directStreamIds"`
+	TimestampUsec   string   `json:"timestampUsec"`
+}
+
+type greaderLink struct {
+	Href string `json:"href"`
+	Type string `json:"type,omitempty"`
+}
+
+type greaderItem struct {
+	ID            string        `json:"id"`
+	CrawlTimeMsec string        `json:"crawlTimeMsec"`
+	TimestampUsec string        `json:"timestampUsec"`
+	Published     int64         `json:"published"`
+	Updated       int64         `json:"updated"`
+	Title         string        `json:"title"`
+	Author        string        `json:"author,omitempty"`
+	Canonical     []greaderLink `json:"canonical"`
+	Alternate     []greaderLink `json:"alternate"`
+	Categories    []string      `json:"categories"`
+	Summary       struct {
+		Direction string `json:"direction"`
+		Content   string `json:"content"`
+	} `json:"summary"`
+	Origin struct {
+		StreamID string `json:"streamId"`
+		Title    string `json:"title"`
+		HtmlUrl  string `json:"htmlUrl"`
+	} `json:"origin"`
+}
+
+func greaderItemID(id int64) string {
+	return fmt.Sprintf("%s%016x", greaderItemPrefix, id)
+}
+
+// clients send item ids back either in the long tag form or as plain decimals
+func parseGReaderItemID(value string) (int64, bool) {
+	if strings.HasPrefix(value, greaderItemPrefix) {
+		id, err := strconv.ParseUint(strings.TrimPrefix(value, greaderItemPrefix), 16, 64)
+		return int64(id), err == nil
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	return id, err == nil
+}
+
+func greaderLabel(category string) string {
+	return greaderLabelPrefix + category
+}
+
+// labels may be sent with the numeric user id in place of "-"
+func greaderLabelName(stream string) (string, bool) {
+	if !strings.HasPrefix(stream, "user/") {
+		return "", false
+	}
+	parts := strings.SplitN(stream, "/", 4)
+	if len(parts) != 4 || parts[2] != "label" {
+		return "", false
+	}
+	return parts[3], true
+}
+
+func (a *apiServer) greaderRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /greader/accounts/ClientLogin", a.handleGReaderLogin)
+	mux.HandleFunc("GET /greader/reader/api/0/token", a.withGReaderUser(a.handleGReaderToken))
+	mux.HandleFunc("GET /greader/reader/api/0/user-info", a.withGReaderUser(a.handleGReaderUserInfo))
+	mux.HandleFunc("GET /greader/reader/api/0/subscription/list", a.withGReaderUser(a.handleGReaderSubscriptions))
+	mux.HandleFunc("GET /greader/reader/api/0/tag/list", a.withGReaderUser(a.handleGReaderTags))
+	mux.HandleFunc("GET /greader/reader/api/0/unread-count", a.withGReaderUser(a.handleGReaderUnreadCount))
+	mux.HandleFunc("GET /greader/reader/api/0/stream/items/ids", a.withGReaderUser(a.handleGReaderItemIDs))
+	mux.HandleFunc("/greader/reader/api/0/stream/items/contents", a.withGReaderUser(a.handleGReaderItemContents))
+	mux.HandleFunc("GET /greader/reader/api/0/stream/contents", a.withGReaderUser(a.handleGReaderStreamContents))
+	mux.HandleFunc("GET /greader/reader/api/0/stream/contents/{stream...}", a.withGReaderUser(a.handleGReaderStreamContents))
+	mux.HandleFunc("POST /greader/reader/api/0/edit-tag", a.withGReaderUser(a.handleGReaderEditTag))
+	mux.HandleFunc("POST /greader/reader/api/0/mark-all-as-read", a.withGReaderUser(a.handleGReaderMarkAllRead))
+}
+
+func (a *apiServer) withGReaderUser(handler func(http.ResponseWriter, *http.Request, database.User)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "GoogleLogin auth=")
+		if !ok || token == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		user, err := a.state.Db.GetUserByGReaderToken(r.Context(), hashToken(token))
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		} else if err != nil {
+			http.Error(w, "database error", http.StatusInternalServerError)
+			return
+		}
+		handler(w, r, user)
+	}
+}
+
+func (a *apiServer) handleGReaderLogin(w http.ResponseWriter, r *http.Request) {
+	user, err := a.state.Db.GetUser(r.Context(), r.FormValue("Email"))
+	if err != nil || !user.PasswordHash.Valid || !checkPassword(r.FormValue("Passwd"), user.PasswordHash.String) {
+		http.Error(w, "Error=BadAuthentication", http.StatusUnauthorized)
+		return
+	}
+	// only the hash is kept, so every sign-in gets a token of its own
+	token, err := newToken()
+	if err != nil {
+		http.Error(w, "error generating token", http.StatusInternalServerError)
+		return
+	}
+	err = a.state.Db.CreateGReaderToken(r.Context(), database.CreateGReaderTokenParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+	})
+	if err != nil {
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "SID=%s\nLSID=%s\nAuth=%s\n", token, token, token)
+}
+
+// edits are authenticated by the Authorization header rather than a cookie,
+// so the action token clients request is only there to keep them happy
+func (a *apiServer) handleGReaderToken(w http.ResponseWriter, r *http.Request, user database.User) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, user.ID.String())
+}
+
+func (a *apiServer) handleGReaderUserInfo(w http.ResponseWriter, r *http.Request, user database.User) {
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"userId":        user.ID.String(),
+		"userName":      user.Name,
+		"userProfileId": user.ID.String(),
+		"userEmail":     "",
+	})
+}
+
+func (a *apiServer) handleGReaderSubscriptions(w http.ResponseWriter, r *http.Request, user database.User) {
+	feeds, err := a.state.Db.GetSubscriptionsForUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithDBError(w, err, "")
+		return
+	}
+	subscriptions := make([]greaderSubscription, 0, len(feeds))
+	for _, feed := range feeds {
+		subscription := greaderSubscription{
+			ID:         greaderFeedPrefix + strconv.FormatInt(feed.NumericID, 10),
+			Title:      feed.Name,
+			Categories: []greaderCategory{},
+			Url:        feed.Url,
+			HtmlUrl:    feed.SiteUrl.String,
+		}
+		if feed.Category.String != "" {
+			subscription.Categories = append(subscription.Categories, greaderCategory{
+				ID:    greaderLabel(feed.Category.String),
+				Label: feed.Category.String,
+			})
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{"subscriptions": subscriptions})
+}
+
+func (a *apiServer) handleGReaderTags(w http.ResponseWriter, r *http.Request, user database.User) {
+	feeds, err := a.state.Db.GetSubscriptionsForUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithDBError(w, err, "")
+		return
+	}
+	tags := []greaderTag{{ID: greaderStarred}}
+	seen := make(map[string]bool)
+	for _, feed := range feeds {
+		if feed.Category.String == "" || seen[feed.Category.String] {
+			continue
+		}
+		seen[feed.Category.String] = true
+		tags = append(tags, greaderTag{ID: greaderLabel(feed.Category.String), Type: "folder"})
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{"tags": tags})
+}
+
+func (a *apiServer) handleGReaderUnreadCount(w http.ResponseWriter, r *http.Request, user database.User) {
+	counts, err := a.state.Db.GetUnreadCountsForUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithDBError(w, err, "")
+		return
+	}
+	var total int64
+	var newest time.Time
+	labels := make(map[string]*greaderUnreadCount)
+	labelNewest := make(map[string]time.Time)
+	var names []string
+	unreadcounts := []greaderUnreadCount{}
+	for _, count := range counts {
+		unreadcounts = append(unreadcounts, greaderUnreadCount{
+			ID:                      greaderFeedPrefix + strconv.FormatInt(count.NumericID, 10),
+			Count:                   count.UnreadCount,
+			NewestItemTimestampUsec: strconv.FormatInt(count.NewestPublishedAt.UnixMicro(), 10),
+		})
+		total += count.UnreadCount
+		if count.NewestPublishedAt.After(newest) {
+			newest = count.NewestPublishedAt
+		}
+		if count.Category.String == "" {
+			continue
+		}
+		label, ok := labels[count.Category.String]
+		if !ok {
+			label = &greaderUnreadCount{ID: greaderLabel(count.Category.String)}
+			labels[count.Category.String] = label
+			names = append(names, count.Category.String)
+		}
+		label.Count += count.UnreadCount
+		if count.NewestPublishedAt.After(labelNewest[count.Category.String]) {
+			labelNewest[count.Category.String] = count.NewestPublishedAt
+		}
+	}
+	for _, name := range names {
+		labels[name].NewestItemTimestampUsec = strconv.FormatInt(labelNewest[name].UnixMicro(), 10)
+		unreadcounts = append(unreadcounts, *labels[name])
+	}
+	unreadcounts = append(unreadcounts, greaderUnreadCount{
+		ID:                      greaderReadingList,
+		Count:                   total,
+		NewestItemTimestampUsec: strconv.FormatInt(newest.UnixMicro(), 10),
+	})
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"max":          total,
+		"unreadcounts": unreadcounts,
+	})
+}
+
+func greaderStream(params *database.GetStreamItemsForUserParams, stream string) error {
+	switch {
+	case stream == "" || stream == greaderReadingList:
+	case stream == greaderStarred:
+		params.StarredOnly = true
+	case stream == greaderRead:
+		params.ReadOnly = true
+	case strings.HasPrefix(stream, greaderFeedPrefix):
+		id, err := strconv.ParseInt(strings.TrimPrefix(stream, greaderFeedPrefix), 10, 64)
+		if err != nil {
+			return fmt.Errorf("unknown feed stream '%s'", stream)
+		}
+		params.FeedNumericID = sql.NullInt64{Int64: id, Valid: true}
+	default:
+		label, ok := greaderLabelName(stream)
+		if !ok {
+			return fmt.Errorf("unsupported stream '%s'", stream)
+		}
+		params.Category = sql.NullString{String: label, Valid: true}
+	}
+	return nil
+}
+
+func greaderStreamParams(r *http.Request, user database.User, stream string) (database.GetStreamItemsForUserParams, error) {
+	params := database.GetStreamItemsForUserParams{
+		UserID:    user.ID,
+		ItemLimit: greaderDefaultItems,
+	}
+	err := greaderStream(&params, stream)
+	if err != nil {
+		return params, err
+	}
+	switch r.FormValue("xt") {
+	case "":
+	case greaderRead:
+		params.UnreadOnly = true
+	default:
+		return params, fmt.Errorf("unsupported exclude target '%s'", r.FormValue("xt"))
+	}
+	switch r.FormValue("it") {
+	case "":
+	case greaderRead:
+		params.ReadOnly = true
+	case greaderStarred:
+		params.StarredOnly = true
+	default:
+		return params, fmt.Errorf("unsupported include target '%s'", r.FormValue("it"))
+	}
+	if value := r.FormValue("n"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return params, errors.New("n must be a positive integer")
+		}
+		params.ItemLimit = int32(min(n, greaderMaxItems))
+	}
+	if value := r.FormValue("c"); value != "" {
+		offset, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || offset < 0 {
+			return params, errors.New("invalid continuation")
+		}
+		params.ItemOffset = int32(offset)
+	}
+	if value := formInt64(r, "ot"); value.Valid {
+		params.NewerThan = sql.NullTime{Time: time.Unix(value.Int64, 0), Valid: true}
+	}
+	if value := formInt64(r, "nt"); value.Valid {
+		params.OlderThan = sql.NullTime{Time: time.Unix(value.Int64, 0), Valid: true}
+	}
+	params.OldestFirst = r.FormValue("r") == "o"
+	return params, nil
+}
+
+// a full page means there may be more, so hand back the next offset
+func greaderContinuation(params database.GetStreamItemsForUserParams, count int) string {
+	if count < int(params.ItemLimit) {
+		return ""
+	}
+	return strconv.Itoa(int(params.ItemOffset) + count)
+}
+
+func toGReaderItem(post database.GetStreamItemsForUserRow) greaderItem {
+	item := greaderItem{
+		ID:            greaderItemID(post.NumericID),
+		CrawlTimeMsec: strconv.FormatInt(post.PublishedAt.UnixMilli(), 10),
+		TimestampUsec: strconv.FormatInt(post.PublishedAt.UnixMicro(), 10),
+		Published:     post.PublishedAt.Unix(),
+		Updated:       post.UpdatedAt.Unix(),
+		Title:         post.Title,
+		Author:        post.Author.String,
+		Canonical:     []greaderLink{{Href: post.Url}},
+		Alternate:     []greaderLink{{Href: post.Url, Type: "text/html"}},
+		Categories:    []string{greaderReadingList},
+	}
+	if post.Category.String != "" {
+		item.Categories = append(item.Categories, greaderLabel(post.Category.String))
+	}
+	if post.IsRead {
+		item.Categories = append(item.Categories, greaderRead)
+	}
+	if post.IsSaved {
+		item.Categories = append(item.Categories, greaderStarred)
+	}
+	item.Summary.Direction = "ltr"
+	item.Summary.Content = post.Description.String
+	item.Origin.StreamID = greaderFeedPrefix + strconv.FormatInt(post.FeedNumericID, 10)
+	item.Origin.Title = post.FeedName
+	item.Origin.HtmlUrl = post.FeedSiteUrl.String
+	return item
+}
+
+func (a *apiServer) handleGReaderItemIDs(w http.ResponseWriter, r *http.Request, user database.User) {
+	params, err := greaderStreamParams(r, user, r.FormValue("s"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	posts, err := a.state.Db.GetStreamItemsForUser(r.Context(), params)
+	if err != nil {
+		respondWithDBError(w, err, "")
+		return
+	}
+	refs := make([]greaderItemRef, 0, len(posts))
+	for _, post := range posts {
+		refs = append(refs, greaderItemRef{
+			ID:              strconv.FormatInt(post.NumericID, 10),
+			DirectStreamIDs: []string{},
+			TimestampUsec:   strconv.FormatInt(post.PublishedAt.UnixMicro(), 10),
+		})
+	}
+	response := map[string]interface{}{"itemRefs": refs}
+	if continuation := greaderContinuation(params, len(posts)); continuation != "" {
+		response["continuation"] = continuation
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (a *apiServer) handleGReaderItemContents(w http.ResponseWriter, r *http.Request, user database.User) {
+	err := r.ParseForm()
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "invalid form")
+		return
+	}
+	var ids []int64
+	for _, value := range r.Form["i"] {
+		id, ok := parseGReaderItemID(value)
+		if ok {
+			ids = append(ids, id)
+		}
+	}
+	items := []greaderItem{}
+	if len(ids) > 0 {
+		posts, err := a.state.Db.GetStreamItemsForUser(r.Context(), database.GetStreamItemsForUserParams{
+			UserID:    user.ID,
+			ItemIds:   ids,
+			ItemLimit: int32(len(ids)),
+		})
+		if err != nil {
+			respondWithDBError(w, err, "")
+			return
+		}
+		for _, post := range posts {
+			items = append(items, toGReaderItem(post))
+		}
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"id":      greaderReadingList,
+		"updated": time.Now().Unix(),
+		"items":   items,
+	})
+}
+
+func (a *apiServer) handleGReaderStreamContents(w http.ResponseWriter, r *http.Request, user database.User) {
+	stream := firstNonEmpty(r.PathValue("stream"), r.FormValue("s"))
+	params, err := greaderStreamParams(r, user, stream)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	posts, err := a.state.Db.GetStreamItemsForUser(r.Context(), params)
+	if err != nil {
+		respondWithDBError(w, err, "")
+		return
+	}
+	items := make([]greaderItem, 0, len(posts))
+	for _, post := range posts {
+		items = append(items, toGReaderItem(post))
+	}
+	response := map[string]interface{}{
+		"id":      firstNonEmpty(stream, greaderReadingList),
+		"updated": time.Now().Unix(),
+		"items":   items,
+	}
+	if continuation := greaderContinuation(params, len(posts)); continuation != "" {
+		response["continuation"] = continuation
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (a *apiServer) handleGReaderEditTag(w http.ResponseWriter, r *http.Request, user database.User) {
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	for _, value := range r.Form["i"] {
+		id, ok := parseGReaderItemID(value)
+		if !ok {
+			continue
+		}
+		post, err := a.state.Db.GetPostByNumericID(r.Context(), id)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		} else if err != nil {
+			http.Error(w, "database error", http.StatusInternalServerError)
+			return
+		}
+		for _, tag := range r.Form["a"] {
+			err = a.greaderTag(r, user, post, tag, true)
+			if err != nil {
+				http.Error(w, "database error", http.StatusInternalServerError)
+				return
+			}
+		}
+		for _, tag := range r.Form["r"] {
+			err = a.greaderTag(r, user, post, tag, false)
+			if err != nil {
+				http.Error(w, "database error", http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, "OK")
+}
+
+func (a *apiServer) greaderTag(r *http.Request, user database.User, post database.GetPostByNumericIDRow, tag string, add bool) error {
+	switch {
+	case tag == greaderRead && add:
+		return a.state.Db.MarkPostRead(r.Context(), database.MarkPostReadParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			UserID:    user.ID,
+			PostID:    post.ID,
+		})
+	case tag == greaderRead:
+		return a.state.Db.MarkPostUnread(r.Context(), database.MarkPostUnreadParams{
+			UserID: user.ID,
+			PostID: post.ID,
+		})
+	case tag == greaderStarred && add:
+		return a.state.Db.SavePost(r.Context(), database.SavePostParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			UserID:    user.ID,
+			PostID:    post.ID,
+		})
+	case tag == greaderStarred:
+		return a.state.Db.UnsavePost(r.Context(), database.UnsavePostParams{
+			UserID: user.ID,
+			PostID: post.ID,
+		})
+	}
+	return nil
+}
+
+func (a *apiServer) handleGReaderMarkAllRead(w http.ResponseWriter, r *http.Request, user database.User) {
+	var stream database.GetStreamItemsForUserParams
+	err := greaderStream(&stream, r.FormValue("s"))
+	if err != nil || stream.StarredOnly || stream.ReadOnly {
+		http.Error(w, "unsupported stream", http.StatusBadRequest)
+		return
+	}
+	before := time.Now()
+	if value := formInt64(r, "ts"); value.Valid {
+		before = time.UnixMicro(value.Int64)
+	}
+	_, err = a.state.Db.MarkPostsReadBefore(r.Context(), database.MarkPostsReadBeforeParams{
+		CreatedAt:     time.Now(),
+		UserID:        user.ID,
+		FeedNumericID: stream.FeedNumericID,
+		Category:      stream.Category,
+		Before:        before,
+	})
+	if err != nil {
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, "OK")
+}
@@ -0,0 +1,209 @@
+package config
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gabeportillo51/blog_aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestParseGReaderItemID(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   int64
+		wantOK bool
+	}{
+		{greaderItemID(42), 42, true},
+		{greaderItemPrefix + "000000000000002a", 42, true},
+		{greaderItemPrefix + "2A", 42, true},
+		{"42", 42, true},
+		{"0", 0, true},
+		{"", 0, false},
+		{"2a", 0, false},
+		{greaderItemPrefix, 0, false},
+		{greaderItemPrefix + "zz", 0, false},
+		{greaderItemPrefix + "10000000000000000", 0, false},
+		{"99999999999999999999", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseGReaderItemID(tt.value)
+		if ok != tt.wantOK || (ok && got != tt.want) {
+			t.Errorf("parseGReaderItemID(%q) = %d, %v, want %d, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestGReaderStreamParams(t *testing.T) {
+	user := database.User{ID: uuid.New()}
+	base := database.GetStreamItemsForUserParams{UserID: user.ID, ItemLimit: greaderDefaultItems}
+	tests := []struct {
+		name    string
+		stream  string
+		query   string
+		want    func(p *database.GetStreamItemsForUserParams)
+		wantErr bool
+	}{
+		{name: "reading list", stream: greaderReadingList},
+		{name: "no stream"},
+		{name: "starred", stream: greaderStarred, want: func(p *database.GetStreamItemsForUserParams) {
+			p.StarredOnly = true
+		}},
+		{name: "read", stream: greaderRead, want: func(p *database.GetStreamItemsForUserParams) {
+			p.ReadOnly = true
+		}},
+		{name: "feed", stream: "feed/7", want: func(p *database.GetStreamItemsForUserParams) {
+			p.FeedNumericID = sql.NullInt64{Int64: 7, Valid: true}
+		}},
+		{name: "bad feed", stream: "feed/https://example.com/rss", wantErr: true},
+		{name: "label", stream: "user/-/label/Tech News", want: func(p *database.GetStreamItemsForUserParams) {
+			p.Category = sql.NullString{String: "Tech News", Valid: true}
+		}},
+		{name: "label with user id", stream: "user/1234/label/go", want: func(p *database.GetStreamItemsForUserParams) {
+			p.Category = sql.NullString{String: "go", Valid: true}
+		}},
+		{name: "unknown stream", stream: "user/-/state/com.google/like", wantErr: true},
+		{name: "exclude read", query: "xt=" + greaderRead, want: func(p *database.GetStreamItemsForUserParams) {
+			p.UnreadOnly = true
+		}},
+		{name: "unknown exclude", query: "xt=" + greaderStarred, wantErr: true},
+		{name: "include starred", query: "it=" + greaderStarred, want: func(p *database.GetStreamItemsForUserParams) {
+			p.StarredOnly = true
+		}},
+		{name: "unknown include", query: "it=" + greaderReadingList, wantErr: true},
+		{name: "count", query: "n=50", want: func(p *database.GetStreamItemsForUserParams) {
+			p.ItemLimit = 50
+		}},
+		{name: "count capped", query: "n=999999", want: func(p *database.GetStreamItemsForUserParams) {
+			p.ItemLimit = greaderMaxItems
+		}},
+		{name: "zero count", query: "n=0", wantErr: true},
+		{name: "bad count", query: "n=ten", wantErr: true},
+		{name: "continuation", query: "c=40", want: func(p *database.GetStreamItemsForUserParams) {
+			p.ItemOffset = 40
+		}},
+		{name: "negative continuation", query: "c=-20", wantErr: true},
+		{name: "continuation past int32", query: "c=4294967336", wantErr: true},
+		{name: "bad continuation", query: "c=next", wantErr: true},
+		{name: "time bounds", query: "ot=1700000000&nt=1800000000", want: func(p *database.GetStreamItemsForUserParams) {
+			p.NewerThan = sql.NullTime{Time: time.Unix(1700000000, 0), Valid: true}
+			p.OlderThan = sql.NullTime{Time: time.Unix(1800000000, 0), Valid: true}
+		}},
+		{name: "oldest first", query: "r=o", want: func(p *database.GetStreamItemsForUserParams) {
+			p.OldestFirst = true
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/greader/reader/api/0/stream/contents?"+tt.query, nil)
+			got, err := greaderStreamParams(r, user, tt.stream)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("greaderStreamParams() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("greaderStreamParams() error = %v", err)
+			}
+			want := base
+			if tt.want != nil {
+				tt.want(&want)
+			}
+			if !streamParamsEqual(got, want) {
+				t.Errorf("greaderStreamParams() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func streamParamsEqual(a, b database.GetStreamItemsForUserParams) bool {
+	return a.UserID == b.UserID && a.FeedNumericID == b.FeedNumericID && a.Category == b.Category &&
+		a.StarredOnly == b.StarredOnly && a.ReadOnly == b.ReadOnly && a.UnreadOnly == b.UnreadOnly &&
+		a.NewerThan.Valid == b.NewerThan.Valid && a.NewerThan.Time.Equal(b.NewerThan.Time) &&
+		a.OlderThan.Valid == b.OlderThan.Valid && a.OlderThan.Time.Equal(b.OlderThan.Time) &&
+		a.OldestFirst == b.OldestFirst && a.ItemLimit == b.ItemLimit && a.ItemOffset == b.ItemOffset &&
+		len(a.ItemIds) == 0 && len(b.ItemIds) == 0
+}
+
+func TestGReaderLogin(t *testing.T) {
+	passwordHash, err := hashPassword("secret")
+	if err != nil {
+		t.Fatalf("hashPassword() error = %v", err)
+	}
+	id := uuid.New()
+	user := []driver.Value{id.String(), time.Now(), time.Now(), "gabe", nil, passwordHash, nil, nil}
+	var stored string
+	db, queries := newFakeDB(t, func(query string, args []driver.Value) [][]driver.Value {
+		switch {
+		case strings.HasPrefix(query, "-- name: GetUser :one"):
+			return [][]driver.Value{user}
+		case strings.HasPrefix(query, "-- name: GetUserByGReaderToken "):
+			if args[0] == stored {
+				return [][]driver.Value{user}
+			}
+		}
+		return nil
+	})
+	mux := (&apiServer{state: &State{Db: queries}}).routes()
+
+	r := httptest.NewRequest("GET", "/greader/accounts/ClientLogin?Email=gabe&Passwd=secret", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET ClientLogin status = %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+
+	form := url.Values{"Email": {"gabe"}, "Passwd": {"wrong"}}
+	r = httptest.NewRequest("POST", "/greader/accounts/ClientLogin", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("wrong password status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	form.Set("Passwd", "secret")
+	r = httptest.NewRequest("POST", "/greader/accounts/ClientLogin", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("login status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	_, token, ok := strings.Cut(w.Body.String(), "Auth=")
+	token = strings.TrimSpace(token)
+	if !ok || token == "" {
+		t.Fatalf("login body = %q, want an Auth token", w.Body)
+	}
+	for _, call := range db.calls {
+		if strings.HasPrefix(call.query, "-- name: CreateGReaderToken ") {
+			stored, _ = call.args[3].(string)
+		}
+	}
+	if stored != hashToken(token) {
+		t.Fatalf("stored token = %q, want the hash of %q", stored, token)
+	}
+
+	r = httptest.NewRequest("GET", "/greader/reader/api/0/user-info", nil)
+	r.Header.Set("Authorization", "GoogleLogin auth="+token)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"userName":"gabe"`) {
+		t.Errorf("user-info = %d %s, want gabe's details", w.Code, w.Body)
+	}
+
+	r = httptest.NewRequest("GET", "/greader/reader/api/0/user-info", nil)
+	r.Header.Set("Authorization", "GoogleLogin auth="+stored)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("user-info with the stored hash status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,232 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gabeportillo51/blog_aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+type OPML struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    struct {
+		Title       string `xml:"title,omitempty"`
+		DateCreated string `xml:"dateCreated,omitempty"`
+	} `xml:"head"`
+	Body struct {
+		Outlines []OPMLOutline `xml:"outline"`
+	} `xml:"body"`
+}
+
+type OPMLOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr,omitempty"`
+	Type     string        `xml:"type,attr,omitempty"`
+	XMLURL   string        `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string        `xml:"htmlUrl,attr,omitempty"`
+	Outlines []OPMLOutline `xml:"outline"`
+}
+
+type opmlSubscription struct {
+	Name     string
+	URL      string
+	SiteURL  string
+	Category string
+}
+
+func collectSubscriptions(outlines []OPMLOutline, folder []string) []opmlSubscription {
+	var subscriptions []opmlSubscription
+	for _, outline := range outlines {
+		if outline.XMLURL != "" {
+			subscriptions = append(subscriptions, opmlSubscription{
+				Name:     firstNonEmpty(outline.Title, outline.Text, outline.XMLURL),
+				URL:      strings.TrimSpace(outline.XMLURL),
+				SiteURL:  strings.TrimSpace(outline.HTMLURL),
+				Category: strings.Join(folder, "/"),
+			})
+			continue
+		}
+		name := firstNonEmpty(outline.Title, outline.Text)
+		nested := folder
+		if name != "" {
+			nested = append(append([]string{}, folder...), name)
+		}
+		subscriptions = append(subscriptions, collectSubscriptions(outline.Outlines, nested)...)
+	}
+	return subscriptions
+}
+
+func HandlerImport(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) != 1 {
+		return errors.New("error: incorrect number of arguments provided to 'import' command")
+	}
+	data, err := os.ReadFile(cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("error reading opml file: %w", err)
+	}
+	var opml OPML
+	err = xml.Unmarshal(data, &opml)
+	if err != nil {
+		return fmt.Errorf("error parsing opml file: %w", err)
+	}
+	feed_follows, err := s.Db.GetFeedFollowsForUser(context.Background(), user.ID)
+	if err != nil {
+		return errors.New("error getting feed-follows for user")
+	}
+	following := make(map[uuid.UUID]bool)
+	for _, feed_follow := range feed_follows {
+		following[feed_follow.FeedID] = true
+	}
+	created, followed, updated := 0, 0, 0
+	for _, subscription := range collectSubscriptions(opml.Body.Outlines, nil) {
+		category := sql.NullString{
+			String: subscription.Category,
+			Valid:  subscription.Category != "",
+		}
+		feed, err := s.Db.GetFeed(context.Background(), subscription.URL)
+		if errors.Is(err, sql.ErrNoRows) {
+			new_feed := database.CreateFeedParams{
+				ID:        uuid.New(),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+				Name:      subscription.Name,
+				Url:       subscription.URL,
+				UserID:    user.ID,
+				SiteUrl: sql.NullString{
+					String: subscription.SiteURL,
+					Valid:  subscription.SiteURL != "",
+				},
+			}
+			feed, err = s.Db.CreateFeed(context.Background(), new_feed)
+			if err != nil {
+				return fmt.Errorf("error creating feed '%s': %w", subscription.URL, err)
+			}
+			created++
+		} else if err != nil {
+			return fmt.Errorf("error getting feed '%s': %w", subscription.URL, err)
+		}
+		if following[feed.ID] {
+			updated++
+			if !category.Valid {
+				continue
+			}
+			update := database.UpdateFeedFollowCategoryParams{
+				UpdatedAt: time.Now(),
+				Category:  category,
+				UserID:    user.ID,
+				FeedID:    feed.ID,
+			}
+			err = s.Db.UpdateFeedFollowCategory(context.Background(), update)
+			if err != nil {
+				return fmt.Errorf("error updating category for feed '%s': %w", feed.Name, err)
+			}
+			continue
+		}
+		feed_follow := database.CreateFeedFollowParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			UserID:    user.ID,
+			FeedID:    feed.ID,
+			Category:  category,
+		}
+		_, err = s.Db.CreateFeedFollow(context.Background(), feed_follow)
+		if err != nil {
+			return fmt.Errorf("error following feed '%s': %w", feed.Name, err)
+		}
+		following[feed.ID] = true
+		followed++
+	}
+	fmt.Printf("Imported %d feed(s): %d created, %d newly followed, %d already followed\n", followed+updated, created, followed, updated)
+	return nil
+}
+
+func HandlerExport(s *State, cmd Command, user database.User) error {
+	output := ""
+	switch {
+	case len(cmd.Args) == 0:
+	case len(cmd.Args) == 1 && strings.HasPrefix(cmd.Args[0], "--output="):
+		output = strings.TrimPrefix(cmd.Args[0], "--output=")
+	case len(cmd.Args) == 2 && cmd.Args[0] == "--output":
+		output = cmd.Args[1]
+	default:
+		return errors.New("error: usage is 'export [--output file]'")
+	}
+	feed_follows, err := s.Db.GetFeedFollowsForUser(context.Background(), user.ID)
+	if err != nil {
+		return errors.New("error getting feed-follows for user")
+	}
+	var opml OPML
+	opml.Version = "2.0"
+	opml.Head.Title = fmt.Sprintf("%s's gator subscriptions", user.Name)
+	opml.Head.DateCreated = time.Now().Format(time.RFC1123Z)
+	folders := make(map[string]*OPMLOutline)
+	var names []string
+	var ungrouped []OPMLOutline
+	for _, feed_follow := range feed_follows {
+		outline := OPMLOutline{
+			Text:    feed_follow.FeedName,
+			Title:   feed_follow.FeedName,
+			Type:    "rss",
+			XMLURL:  feed_follow.FeedUrl,
+			HTMLURL: feed_follow.FeedSiteUrl.String,
+		}
+		if !feed_follow.Category.Valid || feed_follow.Category.String == "" {
+			ungrouped = append(ungrouped, outline)
+			continue
+		}
+		folder, ok := folders[feed_follow.Category.String]
+		if !ok {
+			folder = &OPMLOutline{
+				Text:  feed_follow.Category.String,
+				Title: feed_follow.Category.String,
+			}
+			folders[feed_follow.Category.String] = folder
+			names = append(names, feed_follow.Category.String)
+		}
+		folder.Outlines = append(folder.Outlines, outline)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		opml.Body.Outlines = append(opml.Body.Outlines, *folders[name])
+	}
+	opml.Body.Outlines = append(opml.Body.Outlines, ungrouped...)
+
+	var writer io.Writer = os.Stdout
+	if output != "" {
+		file, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("error creating output file: %w", err)
+		}
+		defer file.Close()
+		writer = file
+	}
+	_, err = io.WriteString(writer, xml.Header)
+	if err != nil {
+		return fmt.Errorf("error writing opml: %w", err)
+	}
+	encoder := xml.NewEncoder(writer)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(opml)
+	if err != nil {
+		return fmt.Errorf("error encoding opml: %w", err)
+	}
+	_, err = io.WriteString(writer, "\n")
+	if err != nil {
+		return fmt.Errorf("error writing opml: %w", err)
+	}
+	if output != "" {
+		fmt.Printf("Exported %d feed(s) to %s\n", len(feed_follows), output)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"encoding/xml"
+	"reflect"
+	"testing"
+)
+
+func TestCollectSubscriptions(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []opmlSubscription
+	}{
+		{
+			name: "flat list",
+			data: `<opml version="2.0"><body>
+  <outline text="Example" title="Example Blog" type="rss" xmlUrl=" https://example.com/feed.xml " htmlUrl="https://example.com/"/>
+  <outline text="" xmlUrl="https://untitled.example/rss"/>
+</body></opml>`,
+			want: []opmlSubscription{
+				{Name: "Example Blog", URL: "https://example.com/feed.xml", SiteURL: "https://example.com/"},
+				{Name: "https://untitled.example/rss", URL: "https://untitled.example/rss"},
+			},
+		},
+		{
+			name: "nested folders",
+			data: `<opml version="1.0"><body>
+  <outline text="Tech">
+    <outline text="Go" title="Go">
+      <outline text="Go Blog" xmlUrl="https://go.dev/blog/feed.atom"/>
+    </outline>
+    <outline text="HN" xmlUrl="https://news.ycombinator.com/rss"/>
+  </outline>
+  <outline text="">
+    <outline text="Loose" xmlUrl="https://loose.example/feed"/>
+  </outline>
+</body></opml>`,
+			want: []opmlSubscription{
+				{Name: "Go Blog", URL: "https://go.dev/blog/feed.atom", Category: "Tech/Go"},
+				{Name: "HN", URL: "https://news.ycombinator.com/rss", Category: "Tech"},
+				{Name: "Loose", URL: "https://loose.example/feed"},
+			},
+		},
+		{
+			name: "empty folders",
+			data: `<opml version="2.0"><body><outline text="Nothing here"/></body></opml>`,
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opml := OPML{}
+			if err := xml.Unmarshal([]byte(tt.data), &opml); err != nil {
+				t.Fatalf("xml.Unmarshal() error = %v", err)
+			}
+			got := collectSubscriptions(opml.Body.Outlines, nil)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("collectSubscriptions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,411 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"mime"
+	"strconv"
+	"strings"
+)
+
+type ParsedFeed struct {
+	Title       string
+	Link        string
+	Description string
+	TTL         int
+	SkipHours   []int
+	SkipDays    []string
+	Items       []FeedItem
+}
+
+type FeedItem struct {
+	ID          string
+	Title       string
+	Link        string
+	Description string
+	PubDate     string
+	Author      string
+}
+
+type RDFFeed struct {
+	Channel struct {
+		Title       string   `xml:"title"`
+		Link        XMLLinks `xml:"link"`
+		Description string   `xml:"description"`
+	} `xml:"channel"`
+	Item []RDFItem `xml:"item"`
+}
+
+type RDFItem struct {
+	About       string   `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	Title       string   `xml:"title"`
+	Link        XMLLinks `xml:"link"`
+	Description string   `xml:"description"`
+	Date        string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+}
+
+const atomNamespace = "http://www.w3.org/2005/Atom"
+
+// XMLLinks collects every <link> of an rss or rdf element, because a tag
+// without a namespace also matches <atom:link rel="self"/> and the last
+// match would otherwise win
+type XMLLinks []struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
+}
+
+func (links XMLLinks) String() string {
+	for _, link := range links {
+		if value := strings.TrimSpace(link.Value); link.XMLName.Space != atomNamespace && value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+type AtomFeed struct {
+	Title    AtomText    `xml:"title"`
+	Subtitle AtomText    `xml:"subtitle"`
+	Links    []AtomLink  `xml:"link"`
+	Entries  []AtomEntry `xml:"entry"`
+}
+
+type AtomEntry struct {
+	ID        string     `xml:"id"`
+	Title     AtomText   `xml:"title"`
+	Links     []AtomLink `xml:"link"`
+	Summary   AtomText   `xml:"summary"`
+	Content   AtomText   `xml:"content"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Authors   []struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+}
+
+type AtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type AtomText struct {
+	Type     string `xml:"type,attr"`
+	Body     string `xml:",chardata"`
+	InnerXML string `xml:",innerxml"`
+}
+
+type JSONFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	Description string         `json:"description"`
+	Items       []JSONFeedItem `json:"items"`
+}
+
+type JSONFeedItem struct {
+	ID            json.RawMessage  `json:"id"`
+	URL           string           `json:"url"`
+	ExternalURL   string           `json:"external_url"`
+	Title         string           `json:"title"`
+	ContentHTML   string           `json:"content_html"`
+	ContentText   string           `json:"content_text"`
+	Summary       string           `json:"summary"`
+	DatePublished string           `json:"date_published"`
+	DateModified  string           `json:"date_modified"`
+	Author        *JSONFeedAuthor  `json:"author"`
+	Authors       []JSONFeedAuthor `json:"authors"`
+}
+
+type JSONFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+// xhtml content is markup, so keep it as-is instead of just the character data
+func (t AtomText) String() string {
+	if t.Type == "xhtml" {
+		return strings.TrimSpace(t.InnerXML)
+	}
+	return strings.TrimSpace(t.Body)
+}
+
+const (
+	formatXML  = "xml"
+	formatJSON = "json"
+)
+
+func parseFeed(contentType string, data []byte) (*ParsedFeed, error) {
+	var parsed *ParsedFeed
+	var err error
+	switch detectFormat(contentType, data) {
+	case formatJSON:
+		parsed, err = parseJSONFeed(data)
+	case formatXML:
+		parsed, err = parseXMLFeed(data)
+	default:
+		return nil, fmt.Errorf("unable to detect feed format (content type %q)", contentType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	parsed.Title = html.UnescapeString(parsed.Title)
+	parsed.Description = html.UnescapeString(parsed.Description)
+	for i := range parsed.Items {
+		parsed.Items[i].Title = html.UnescapeString(parsed.Items[i].Title)
+		parsed.Items[i].Description = html.UnescapeString(parsed.Items[i].Description)
+	}
+	return parsed, nil
+}
+
+func detectFormat(contentType string, data []byte) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		switch {
+		case mediaType == "application/feed+json", mediaType == "application/json", strings.HasSuffix(mediaType, "+json"):
+			return formatJSON
+		case mediaType == "application/xml", mediaType == "text/xml", strings.HasSuffix(mediaType, "+xml"):
+			return formatXML
+		}
+	}
+	trimmed := bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	trimmed = bytes.TrimLeft(trimmed, " \t\r\n")
+	if len(trimmed) == 0 {
+		return ""
+	}
+	switch trimmed[0] {
+	case '{':
+		return formatJSON
+	case '<':
+		return formatXML
+	}
+	return ""
+}
+
+func parseXMLFeed(data []byte) (*ParsedFeed, error) {
+	root, err := rootElement(data)
+	if err != nil {
+		return nil, fmt.Errorf("error reading root element: %w", err)
+	}
+	switch root.Local {
+	case "rss":
+		return parseRSS(data)
+	case "feed":
+		return parseAtom(data)
+	case "RDF":
+		return parseRDF(data)
+	}
+	return nil, fmt.Errorf("unsupported feed format: <%s>", root.Local)
+}
+
+func rootElement(data []byte) (xml.Name, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return xml.Name{}, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name, nil
+		}
+	}
+}
+
+func parseRSS(data []byte) (*ParsedFeed, error) {
+	rssfeed := &RSSFeed{}
+	err := xml.Unmarshal(data, rssfeed)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling: %w", err)
+	}
+	parsed := &ParsedFeed{
+		Title:       rssfeed.Channel.Title,
+		Link:        rssfeed.Channel.Link.String(),
+		Description: rssfeed.Channel.Description,
+	}
+	ttl, err := strconv.Atoi(strings.TrimSpace(rssfeed.Channel.TTL))
+	if err == nil && ttl > 0 {
+		parsed.TTL = ttl
+	}
+	for _, value := range rssfeed.Channel.SkipHours {
+		hour, err := strconv.Atoi(strings.TrimSpace(value))
+		if err == nil && hour >= 0 && hour <= 24 {
+			parsed.SkipHours = append(parsed.SkipHours, hour%24)
+		}
+	}
+	for _, day := range rssfeed.Channel.SkipDays {
+		if day = strings.TrimSpace(day); day != "" {
+			parsed.SkipDays = append(parsed.SkipDays, day)
+		}
+	}
+	for _, item := range rssfeed.Channel.Item {
+		parsed.Items = append(parsed.Items, FeedItem{
+			ID:          strings.TrimSpace(item.GUID),
+			Title:       item.Title,
+			Link:        item.Link.String(),
+			Description: item.Description,
+			PubDate:     firstNonEmpty(item.PubDate, item.Date),
+			Author:      firstNonEmpty(item.Creator, item.Author),
+		})
+	}
+	return parsed, nil
+}
+
+func parseRDF(data []byte) (*ParsedFeed, error) {
+	rdffeed := &RDFFeed{}
+	err := xml.Unmarshal(data, rdffeed)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling: %w", err)
+	}
+	parsed := &ParsedFeed{
+		Title:       rdffeed.Channel.Title,
+		Link:        rdffeed.Channel.Link.String(),
+		Description: rdffeed.Channel.Description,
+	}
+	for _, item := range rdffeed.Item {
+		parsed.Items = append(parsed.Items, FeedItem{
+			ID:          strings.TrimSpace(item.About),
+			Title:       item.Title,
+			Link:        firstNonEmpty(item.Link.String(), item.About),
+			Description: item.Description,
+			PubDate:     strings.TrimSpace(item.Date),
+			Author:      strings.TrimSpace(item.Creator),
+		})
+	}
+	return parsed, nil
+}
+
+func parseAtom(data []byte) (*ParsedFeed, error) {
+	atomfeed := &AtomFeed{}
+	err := xml.Unmarshal(data, atomfeed)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling: %w", err)
+	}
+	parsed := &ParsedFeed{
+		Title:       atomfeed.Title.String(),
+		Link:        alternateLink(atomfeed.Links),
+		Description: atomfeed.Subtitle.String(),
+	}
+	for _, entry := range atomfeed.Entries {
+		description := entry.Summary.String()
+		if description == "" {
+			description = entry.Content.String()
+		}
+		published := strings.TrimSpace(entry.Published)
+		if published == "" {
+			published = strings.TrimSpace(entry.Updated)
+		}
+		link := alternateLink(entry.Links)
+		if link == "" && (strings.HasPrefix(entry.ID, "http://") || strings.HasPrefix(entry.ID, "https://")) {
+			link = strings.TrimSpace(entry.ID)
+		}
+		author := ""
+		if len(entry.Authors) > 0 {
+			author = strings.TrimSpace(entry.Authors[0].Name)
+		}
+		parsed.Items = append(parsed.Items, FeedItem{
+			ID:          strings.TrimSpace(entry.ID),
+			Title:       entry.Title.String(),
+			Link:        link,
+			Description: description,
+			PubDate:     published,
+			Author:      author,
+		})
+	}
+	return parsed, nil
+}
+
+func alternateLink(links []AtomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return strings.TrimSpace(link.Href)
+		}
+	}
+	if len(links) > 0 {
+		return strings.TrimSpace(links[0].Href)
+	}
+	return ""
+}
+
+func parseJSONFeed(data []byte) (*ParsedFeed, error) {
+	jsonfeed := &JSONFeed{}
+	err := json.Unmarshal(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), jsonfeed)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling: %w", err)
+	}
+	if !strings.HasPrefix(jsonfeed.Version, "https://jsonfeed.org/version/1") {
+		return nil, errors.New("unsupported json feed version: " + jsonfeed.Version)
+	}
+	parsed := &ParsedFeed{
+		Title:       jsonfeed.Title,
+		Link:        jsonfeed.HomePageURL,
+		Description: jsonfeed.Description,
+	}
+	for _, item := range jsonfeed.Items {
+		link := item.URL
+		if link == "" {
+			link = item.ExternalURL
+		}
+		description := item.ContentHTML
+		if description == "" {
+			description = item.ContentText
+		}
+		if item.Summary != "" {
+			description = item.Summary
+		}
+		title := item.Title
+		if title == "" {
+			title = untitled(item.ContentText)
+		}
+		published := item.DatePublished
+		if published == "" {
+			published = item.DateModified
+		}
+		author := ""
+		if len(item.Authors) > 0 {
+			author = item.Authors[0].Name
+		} else if item.Author != nil {
+			author = item.Author.Name
+		}
+		parsed.Items = append(parsed.Items, FeedItem{
+			ID:          jsonFeedID(item.ID),
+			Title:       title,
+			Link:        link,
+			Description: description,
+			PubDate:     published,
+			Author:      author,
+		})
+	}
+	return parsed, nil
+}
+
+// version 1 allowed numeric ids, 1.1 requires strings
+func jsonFeedID(raw json.RawMessage) string {
+	var id string
+	if err := json.Unmarshal(raw, &id); err == nil {
+		return id
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+// micro-blog items usually have no title, so use the start of the text
+func untitled(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) > 80 {
+		return string(runes[:80]) + "..."
+	}
+	return text
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
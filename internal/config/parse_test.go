@@ -0,0 +1,444 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFeedAtom(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want *ParsedFeed
+	}{
+		{
+			name: "entries with alternate links",
+			data: `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Example &amp;amp; Co</title>
+  <subtitle>All the news</subtitle>
+  <link href="https://example.com/feed.atom" rel="self"/>
+  <link href="https://example.com/"/>
+  <entry>
+    <id>tag:example.com,2024:1</id>
+    <title>First post</title>
+    <link href="https://example.com/1" rel="alternate"/>
+    <summary>Short version</summary>
+    <content type="html">&lt;p&gt;Long version&lt;/p&gt;</content>
+    <published>2024-01-02T03:04:05Z</published>
+    <updated>2024-01-03T00:00:00Z</updated>
+    <author><name> Jane Doe </name></author>
+  </entry>
+</feed>`,
+			want: &ParsedFeed{
+				Title:       "Example & Co",
+				Link:        "https://example.com/",
+				Description: "All the news",
+				Items: []FeedItem{{
+					ID:          "tag:example.com,2024:1",
+					Title:       "First post",
+					Link:        "https://example.com/1",
+					Description: "Short version",
+					PubDate:     "2024-01-02T03:04:05Z",
+					Author:      "Jane Doe",
+				}},
+			},
+		},
+		{
+			name: "content, updated and id fall back",
+			data: `<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Fallbacks</title>
+  <entry>
+    <id>https://example.com/2</id>
+    <title type="html">A &amp;lt;b&amp;gt;bold&amp;lt;/b&amp;gt; title</title>
+    <content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>Markup</p></div></content>
+    <updated>2024-02-01T00:00:00Z</updated>
+  </entry>
+</feed>`,
+			want: &ParsedFeed{
+				Title: "Fallbacks",
+				Items: []FeedItem{{
+					ID:          "https://example.com/2",
+					Title:       "A <b>bold</b> title",
+					Link:        "https://example.com/2",
+					Description: `<div xmlns="http://www.w3.org/1999/xhtml"><p>Markup</p></div>`,
+					PubDate:     "2024-02-01T00:00:00Z",
+				}},
+			},
+		},
+		{
+			name: "only a non-alternate link",
+			data: `<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Enclosures</title>
+  <entry>
+    <id>urn:uuid:1</id>
+    <title>Episode</title>
+    <link href="https://example.com/1.mp3" rel="enclosure"/>
+  </entry>
+</feed>`,
+			want: &ParsedFeed{
+				Title: "Enclosures",
+				Items: []FeedItem{{
+					ID:    "urn:uuid:1",
+					Title: "Episode",
+					Link:  "https://example.com/1.mp3",
+				}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFeed("application/atom+xml", []byte(tt.data))
+			if err != nil {
+				t.Fatalf("parseFeed() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseFeed() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFeedJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    *ParsedFeed
+		wantErr bool
+	}{
+		{
+			name: "version 1.1",
+			data: `{
+  "version": "https://jsonfeed.org/version/1.1",
+  "title": "JSON &amp; Friends",
+  "home_page_url": "https://example.com/",
+  "description": "A json feed",
+  "items": [
+    {
+      "id": "1",
+      "url": "https://example.com/1",
+      "title": "First",
+      "summary": "Summary wins",
+      "content_html": "<p>html</p>",
+      "content_text": "text",
+      "date_published": "2024-01-02T03:04:05Z",
+      "date_modified": "2024-01-03T00:00:00Z",
+      "authors": [{"name": "Jane"}, {"name": "John"}],
+      "author": {"name": "Ignored"}
+    },
+    {
+      "id": "2",
+      "external_url": "https://elsewhere.example/2",
+      "content_html": "<p>html</p>",
+      "content_text": "text",
+      "date_modified": "2024-01-04T00:00:00Z"
+    }
+  ]
+}`,
+			want: &ParsedFeed{
+				Title:       "JSON & Friends",
+				Link:        "https://example.com/",
+				Description: "A json feed",
+				Items: []FeedItem{
+					{
+						ID:          "1",
+						Title:       "First",
+						Link:        "https://example.com/1",
+						Description: "Summary wins",
+						PubDate:     "2024-01-02T03:04:05Z",
+						Author:      "Jane",
+					},
+					{
+						ID:          "2",
+						Title:       "text",
+						Link:        "https://elsewhere.example/2",
+						Description: "<p>html</p>",
+						PubDate:     "2024-01-04T00:00:00Z",
+					},
+				},
+			},
+		},
+		{
+			name: "version 1 with numeric id and untitled item",
+			data: "\xef\xbb\xbf" + `{
+  "version": "https://jsonfeed.org/version/1",
+  "title": "Microblog",
+  "items": [
+    {
+      "id": 42,
+      "url": "https://example.com/42",
+      "content_text": "  just   a\nshort note  ",
+      "author": {"name": "Jane"}
+    }
+  ]
+}`,
+			want: &ParsedFeed{
+				Title: "Microblog",
+				Items: []FeedItem{{
+					ID:          "42",
+					Title:       "just a short note",
+					Link:        "https://example.com/42",
+					Description: "  just   a\nshort note  ",
+					Author:      "Jane",
+				}},
+			},
+		},
+		{
+			name:    "unknown version",
+			data:    `{"version": "https://jsonfeed.org/version/2", "title": "Future"}`,
+			wantErr: true,
+		},
+		{
+			name:    "not a json feed",
+			data:    `{"hello": "world"}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFeed("application/feed+json", []byte(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseFeed() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFeed() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseFeed() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUntitled(t *testing.T) {
+	long := strings.Repeat("é", 100)
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"", ""},
+		{" one\ttwo\n three ", "one two three"},
+		{long, strings.Repeat("é", 80) + "..."},
+	}
+	for _, tt := range tests {
+		if got := untitled(tt.text); got != tt.want {
+			t.Errorf("untitled(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		data        string
+		want        string
+	}{
+		{"json feed media type", "application/feed+json; charset=utf-8", "<rss/>", formatJSON},
+		{"plain json media type", "application/json", "", formatJSON},
+		{"rss media type", "application/rss+xml", "{}", formatXML},
+		{"atom media type", "application/atom+xml", "", formatXML},
+		{"text xml", "text/xml; charset=iso-8859-1", "", formatXML},
+		{"sniffed json", "text/plain", "\n  {\"version\": \"\"}", formatJSON},
+		{"sniffed xml", "text/html", "<?xml version=\"1.0\"?><rss/>", formatXML},
+		{"sniffed behind a bom", "", "\xef\xbb\xbf<feed/>", formatXML},
+		{"sniffed json behind a bom", "application/octet-stream", "\xef\xbb\xbf{}", formatJSON},
+		{"invalid media type", "not a type;;", "<rss/>", formatXML},
+		{"empty body", "", " \r\n", ""},
+		{"unknown body", "text/plain", "hello", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectFormat(tt.contentType, []byte(tt.data)); got != tt.want {
+				t.Errorf("detectFormat(%q, %q) = %q, want %q", tt.contentType, tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFeedRSS(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want *ParsedFeed
+	}{
+		{
+			name: "plain rss 2.0",
+			data: `<?xml version="1.0"?>
+<rss version="2.0">
+  <channel>
+    <title>Plain &amp;amp; Simple</title>
+    <link>https://example.com/</link>
+    <description>&lt;b&gt;News&lt;/b&gt;</description>
+    <item>
+      <title>First</title>
+      <link>https://example.com/1</link>
+      <description>Body</description>
+      <pubDate>Tue, 02 Jan 2024 03:04:05 GMT</pubDate>
+      <guid isPermaLink="false"> abc-1 </guid>
+      <author>jane@example.com (Jane)</author>
+    </item>
+  </channel>
+</rss>`,
+			want: &ParsedFeed{
+				Title:       "Plain & Simple",
+				Link:        "https://example.com/",
+				Description: "<b>News</b>",
+				Items: []FeedItem{{
+					ID:          "abc-1",
+					Title:       "First",
+					Link:        "https://example.com/1",
+					Description: "Body",
+					PubDate:     "Tue, 02 Jan 2024 03:04:05 GMT",
+					Author:      "jane@example.com (Jane)",
+				}},
+			},
+		},
+		{
+			name: "dublin core dates and creators",
+			data: `<rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <channel>
+    <title>Dublin Core</title>
+    <link>https://example.com/</link>
+    <item>
+      <title>Dated</title>
+      <link>https://example.com/1</link>
+      <dc:date>2024-01-02T03:04:05Z</dc:date>
+      <dc:creator>Jane Doe</dc:creator>
+      <author>ignored@example.com</author>
+    </item>
+    <item>
+      <title>Both dates</title>
+      <link>https://example.com/2</link>
+      <pubDate>Wed, 03 Jan 2024 00:00:00 GMT</pubDate>
+      <dc:date>2024-01-01T00:00:00Z</dc:date>
+    </item>
+  </channel>
+</rss>`,
+			want: &ParsedFeed{
+				Title: "Dublin Core",
+				Link:  "https://example.com/",
+				Items: []FeedItem{
+					{
+						Title:   "Dated",
+						Link:    "https://example.com/1",
+						PubDate: "2024-01-02T03:04:05Z",
+						Author:  "Jane Doe",
+					},
+					{
+						Title:   "Both dates",
+						Link:    "https://example.com/2",
+						PubDate: "Wed, 03 Jan 2024 00:00:00 GMT",
+					},
+				},
+			},
+		},
+		{
+			name: "atom self link does not replace the channel link",
+			data: `<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+  <channel>
+    <title>Self linked</title>
+    <link>https://example.com/</link>
+    <atom:link href="https://example.com/feed.xml" rel="self" type="application/rss+xml"/>
+    <item>
+      <title>Post</title>
+      <atom:link href="https://example.com/ignored" rel="related"/>
+      <link>https://example.com/1</link>
+    </item>
+  </channel>
+</rss>`,
+			want: &ParsedFeed{
+				Title: "Self linked",
+				Link:  "https://example.com/",
+				Items: []FeedItem{{
+					Title: "Post",
+					Link:  "https://example.com/1",
+				}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFeed("application/rss+xml", []byte(tt.data))
+			if err != nil {
+				t.Fatalf("parseFeed() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseFeed() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFeedRDF(t *testing.T) {
+	data := `<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+         xmlns="http://purl.org/rss/1.0/"
+         xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <channel rdf:about="https://example.com/">
+    <title>RSS 1.0</title>
+    <link>https://example.com/</link>
+    <description>An rdf feed</description>
+  </channel>
+  <item rdf:about="https://example.com/1">
+    <title>Linked</title>
+    <link>https://example.com/1?ref=rss</link>
+    <description>First</description>
+    <dc:date>2024-01-02T03:04:05Z</dc:date>
+    <dc:creator> Jane Doe </dc:creator>
+  </item>
+  <item rdf:about="https://example.com/2">
+    <title>Unlinked</title>
+  </item>
+</rdf:RDF>`
+	want := &ParsedFeed{
+		Title:       "RSS 1.0",
+		Link:        "https://example.com/",
+		Description: "An rdf feed",
+		Items: []FeedItem{
+			{
+				ID:          "https://example.com/1",
+				Title:       "Linked",
+				Link:        "https://example.com/1?ref=rss",
+				Description: "First",
+				PubDate:     "2024-01-02T03:04:05Z",
+				Author:      "Jane Doe",
+			},
+			{
+				ID:    "https://example.com/2",
+				Title: "Unlinked",
+				Link:  "https://example.com/2",
+			},
+		},
+	}
+	got, err := parseFeed("application/rdf+xml", []byte(data))
+	if err != nil {
+		t.Fatalf("parseFeed() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseFeed() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseFeedUnsupported(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		data        string
+	}{
+		{"unknown root element", "text/xml", "<html><body/></html>"},
+		{"not xml", "application/rss+xml", "<<<"},
+		{"no format", "text/plain", "hello"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := parseFeed(tt.contentType, []byte(tt.data)); err == nil {
+				t.Errorf("parseFeed() = %+v, want an error", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gabeportillo51/blog_aggregator/internal/database"
+)
+
+const (
+	outputRSS      = "rss"
+	outputAtom     = "atom"
+	outputFeedSize = 50
+)
+
+type outputRSSFeed struct {
+	XMLName xml.Name `xml:"rss"`
+	Version string   `xml:"version,attr"`
+	DC      string   `xml:"xmlns:dc,attr"`
+	Channel struct {
+		Title         string          `xml:"title"`
+		Link          string          `xml:"link"`
+		Description   string          `xml:"description"`
+		LastBuildDate string          `xml:"lastBuildDate"`
+		Generator     string          `xml:"generator"`
+		Items         []outputRSSItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type outputRSSItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description,omitempty"`
+	Creator     string `xml:"dc:creator,omitempty"`
+	Category    string `xml:"category,omitempty"`
+	GUID        struct {
+		IsPermaLink bool   `xml:"isPermaLink,attr"`
+		Value       string `xml:",chardata"`
+	} `xml:"guid"`
+	PubDate string `xml:"pubDate"`
+}
+
+type outputAtomFeed struct {
+	XMLName   xml.Name          `xml:"http://www.w3.org/2005/Atom feed"`
+	Title     string            `xml:"title"`
+	ID        string            `xml:"id"`
+	Updated   string            `xml:"updated"`
+	Generator string            `xml:"generator"`
+	Links     []outputAtomLink  `xml:"link"`
+	Entries   []outputAtomEntry `xml:"entry"`
+}
+
+type outputAtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type outputAtomEntry struct {
+	Title     string           `xml:"title"`
+	ID        string           `xml:"id"`
+	Links     []outputAtomLink `xml:"link"`
+	Published string           `xml:"published"`
+	Updated   string           `xml:"updated"`
+	Author    struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+	Category *outputAtomCategory `xml:"category"`
+	Summary  *outputAtomText     `xml:"summary"`
+}
+
+type outputAtomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type outputAtomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+func writeOutputFeed(w io.Writer, format string, user database.User, posts []database.GetPostsForUserRow, self string) error {
+	title := fmt.Sprintf("%s's gator timeline", user.Name)
+	var feed interface{}
+	switch format {
+	case outputRSS:
+		rss := outputRSSFeed{Version: "2.0", DC: "http://purl.org/dc/elements/1.1/"}
+		rss.Channel.Title = title
+		rss.Channel.Link = self
+		rss.Channel.Description = fmt.Sprintf("Posts from every feed %s follows", user.Name)
+		rss.Channel.LastBuildDate = time.Now().Format(time.RFC1123Z)
+		rss.Channel.Generator = "gator"
+		for _, post := range posts {
+			item := outputRSSItem{
+				Title:       post.Title,
+				Link:        post.Url,
+				Description: post.Description.String,
+				Creator:     post.Author.String,
+				Category:    post.FeedName,
+				PubDate:     post.PublishedAt.Format(time.RFC1123Z),
+			}
+			item.GUID.Value = "urn:uuid:" + post.ID.String()
+			rss.Channel.Items = append(rss.Channel.Items, item)
+		}
+		feed = rss
+	case outputAtom:
+		atom := outputAtomFeed{
+			Title:     title,
+			ID:        "urn:uuid:" + user.ID.String(),
+			Updated:   time.Now().Format(time.RFC3339),
+			Generator: "gator",
+		}
+		if self != "" {
+			atom.Links = append(atom.Links, outputAtomLink{Href: self, Rel: "self"})
+		}
+		for _, post := range posts {
+			entry := outputAtomEntry{
+				Title:     post.Title,
+				ID:        "urn:uuid:" + post.ID.String(),
+				Links:     []outputAtomLink{{Href: post.Url, Rel: "alternate"}},
+				Published: post.PublishedAt.Format(time.RFC3339),
+				Updated:   post.UpdatedAt.Format(time.RFC3339),
+			}
+			// atom requires an author, so fall back to the name of the feed
+			entry.Author.Name = firstNonEmpty(post.Author.String, post.FeedName)
+			entry.Category = &outputAtomCategory{Term: post.FeedName}
+			if post.Description.String != "" {
+				entry.Summary = &outputAtomText{Type: "html", Body: post.Description.String}
+			}
+			atom.Entries = append(atom.Entries, entry)
+		}
+		feed = atom
+	default:
+		return fmt.Errorf("unknown output feed format '%s' (expected rss or atom)", format)
+	}
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(feed)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+func outputFeedPosts(ctx context.Context, s *State, user database.User) ([]database.GetPostsForUserRow, error) {
+	return s.Db.GetPostsForUser(ctx, database.GetPostsForUserParams{
+		UserID:    user.ID,
+		PostLimit: outputFeedSize,
+	})
+}
+
+func HandlerPublish(s *State, cmd Command, user database.User) error {
+	usage := errors.New("error: usage is 'publish <rss|atom> --link <url> [--output file]'")
+	if len(cmd.Args) == 0 {
+		return usage
+	}
+	format := cmd.Args[0]
+	if format != outputRSS && format != outputAtom {
+		return fmt.Errorf("unknown output feed format '%s' (expected rss or atom)", format)
+	}
+	output, link := "", ""
+	args := cmd.Args[1:]
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--output" || arg == "--link":
+			if i+1 >= len(args) {
+				return fmt.Errorf("no value provided for %s", arg)
+			}
+			if arg == "--output" {
+				output = args[i+1]
+			} else {
+				link = args[i+1]
+			}
+			i++
+		case strings.HasPrefix(arg, "--output="):
+			output = strings.TrimPrefix(arg, "--output=")
+		case strings.HasPrefix(arg, "--link="):
+			link = strings.TrimPrefix(arg, "--link=")
+		default:
+			return usage
+		}
+	}
+	// rss readers need the channel link, so there is no sensible default
+	// for a feed written out to be hosted somewhere else
+	if link == "" {
+		return errors.New("error: publish needs --link <url>, the address the feed will be served from")
+	}
+	parsed, err := url.Parse(link)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("error: --link must be an absolute http(s) url, not '%s'", link)
+	}
+	posts, err := outputFeedPosts(context.Background(), s, user)
+	if err != nil {
+		return errors.New("error getting posts")
+	}
+	var writer io.Writer = os.Stdout
+	if output != "" {
+		file, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("error creating output file: %w", err)
+		}
+		defer file.Close()
+		writer = file
+	}
+	err = writeOutputFeed(writer, format, user, posts, link)
+	if err != nil {
+		return fmt.Errorf("error writing %s feed: %w", format, err)
+	}
+	if output != "" {
+		fmt.Printf("Published %d post(s) to %s\n", len(posts), output)
+	}
+	return nil
+}
+
+// only the hash of the feed token is stored, so like the API token it is
+// shown once when it is created
+func HandlerFeedToken(s *State, cmd Command, user database.User) error {
+	reset := false
+	switch {
+	case len(cmd.Args) == 0:
+	case len(cmd.Args) == 1 && cmd.Args[0] == "--reset":
+		reset = true
+	default:
+		return errors.New("error: usage is 'feedtoken [--reset]'")
+	}
+	if user.FeedTokenHash.Valid && !reset {
+		fmt.Printf("%s already has a feed token; run 'feedtoken --reset' to replace it\n", user.Name)
+		return nil
+	}
+	token, err := newToken()
+	if err != nil {
+		return fmt.Errorf("error generating feed token: %w", err)
+	}
+	err = s.Db.UpdateUserFeedTokenHash(context.Background(), database.UpdateUserFeedTokenHashParams{
+		ID:            user.ID,
+		UpdatedAt:     time.Now(),
+		FeedTokenHash: sql.NullString{String: hashToken(token), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("error saving feed token: %w", err)
+	}
+	fmt.Printf("Feed token for %s: %s\n", user.Name, token)
+	fmt.Printf("It is only shown once. With 'gator serve' running, subscribe to:\n")
+	fmt.Printf(" * /output/%s/rss.xml\n", token)
+	fmt.Printf(" * /output/%s/atom.xml\n", token)
+	return nil
+}
+
+func (a *apiServer) handleOutputFeed(w http.ResponseWriter, r *http.Request) {
+	var format, contentType string
+	switch r.PathValue("file") {
+	case "rss.xml":
+		format, contentType = outputRSS, "application/rss+xml; charset=utf-8"
+	case "atom.xml":
+		format, contentType = outputAtom, "application/atom+xml; charset=utf-8"
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	token := r.PathValue("token")
+	user, err := a.state.Db.GetUserByFeedTokenHash(r.Context(), sql.NullString{String: hashToken(token), Valid: true})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+	posts, err := outputFeedPosts(r.Context(), a.state, user)
+	if err != nil {
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	self := fmt.Sprintf("%s://%s%s", scheme, r.Host, r.URL.Path)
+	w.Header().Set("Content-Type", contentType)
+	err = writeOutputFeed(w, format, user, posts, self)
+	if err != nil {
+		// the headers are already out, so all that is left is to say so
+		fmt.Fprintf(os.Stderr, "error writing %s feed for %s: %v\n", format, user.Name, err)
+	}
+}
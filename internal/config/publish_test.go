@@ -0,0 +1,144 @@
+package config
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gabeportillo51/blog_aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+func testOutputPosts() []database.GetPostsForUserRow {
+	published := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	return []database.GetPostsForUserRow{
+		{
+			ID:          uuid.MustParse("11111111-1111-1111-1111-111111111111"),
+			UpdatedAt:   published.Add(time.Hour),
+			Title:       "Fish & <Chips>",
+			Url:         "https://example.com/fish",
+			Description: sql.NullString{String: "<p>battered</p>", Valid: true},
+			Author:      sql.NullString{String: "Ada", Valid: true},
+			PublishedAt: published,
+			FeedName:    "Example",
+		},
+		{
+			ID:          uuid.MustParse("22222222-2222-2222-2222-222222222222"),
+			UpdatedAt:   published,
+			Title:       "No author",
+			Url:         "https://example.com/bare",
+			PublishedAt: published,
+			FeedName:    "Example",
+		},
+	}
+}
+
+func TestWriteOutputFeedRSS(t *testing.T) {
+	user := database.User{ID: uuid.New(), Name: "gabe"}
+	var buf bytes.Buffer
+	err := writeOutputFeed(&buf, outputRSS, user, testOutputPosts(), "https://gator.example/timeline.xml")
+	if err != nil {
+		t.Fatalf("writeOutputFeed() error = %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), xml.Header) {
+		t.Errorf("output does not start with the xml header: %q", buf.String()[:40])
+	}
+	var got outputRSSFeed
+	err = xml.Unmarshal(buf.Bytes(), &got)
+	if err != nil {
+		t.Fatalf("output is not valid xml: %v", err)
+	}
+	if got.Version != "2.0" || got.Channel.Title != "gabe's gator timeline" {
+		t.Errorf("version, title = %q, %q", got.Version, got.Channel.Title)
+	}
+	if got.Channel.Link != "https://gator.example/timeline.xml" {
+		t.Errorf("channel link = %q, want the published address", got.Channel.Link)
+	}
+	if len(got.Channel.Items) != 2 {
+		t.Fatalf("got %d items, want 2", len(got.Channel.Items))
+	}
+	item := got.Channel.Items[0]
+	if item.Title != "Fish & <Chips>" || item.Link != "https://example.com/fish" || item.Description != "<p>battered</p>" {
+		t.Errorf("item = %+v, want the post's title, link and description", item)
+	}
+	if item.Category != "Example" || item.PubDate != "Fri, 01 Mar 2024 09:30:00 +0000" {
+		t.Errorf("category, pubDate = %q, %q", item.Category, item.PubDate)
+	}
+	// the dc prefix does not survive decoding, so look for it as written
+	if strings.Count(buf.String(), "<dc:creator>") != 1 || !strings.Contains(buf.String(), "<dc:creator>Ada</dc:creator>") {
+		t.Errorf("output should credit Ada once as dc:creator:\n%s", buf.String())
+	}
+	if item.GUID.IsPermaLink || item.GUID.Value != "urn:uuid:11111111-1111-1111-1111-111111111111" {
+		t.Errorf("guid = %+v, want a non-permalink urn", item.GUID)
+	}
+}
+
+func TestWriteOutputFeedAtom(t *testing.T) {
+	user := database.User{ID: uuid.MustParse("33333333-3333-3333-3333-333333333333"), Name: "gabe"}
+	var buf bytes.Buffer
+	err := writeOutputFeed(&buf, outputAtom, user, testOutputPosts(), "https://gator.example/atom.xml")
+	if err != nil {
+		t.Fatalf("writeOutputFeed() error = %v", err)
+	}
+	var got outputAtomFeed
+	err = xml.Unmarshal(buf.Bytes(), &got)
+	if err != nil {
+		t.Fatalf("output is not valid xml: %v", err)
+	}
+	if got.ID != "urn:uuid:33333333-3333-3333-3333-333333333333" {
+		t.Errorf("feed id = %q, want the user's urn", got.ID)
+	}
+	if len(got.Links) != 1 || got.Links[0] != (outputAtomLink{Href: "https://gator.example/atom.xml", Rel: "self"}) {
+		t.Errorf("links = %+v, want one self link", got.Links)
+	}
+	if len(got.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(got.Entries))
+	}
+	first, second := got.Entries[0], got.Entries[1]
+	if first.Author.Name != "Ada" || first.Summary == nil || first.Summary.Body != "<p>battered</p>" {
+		t.Errorf("first entry = %+v, want its author and an html summary", first)
+	}
+	if first.Updated != "2024-03-01T10:30:00Z" || first.Published != "2024-03-01T09:30:00Z" {
+		t.Errorf("published, updated = %q, %q", first.Published, first.Updated)
+	}
+	// atom needs an author, so the feed name stands in
+	if second.Author.Name != "Example" || second.Summary != nil {
+		t.Errorf("second entry = %+v, want the feed as author and no summary", second)
+	}
+}
+
+func TestWriteOutputFeedUnknownFormat(t *testing.T) {
+	var buf bytes.Buffer
+	err := writeOutputFeed(&buf, "json", database.User{}, nil, "https://gator.example/")
+	if err == nil {
+		t.Fatalf("writeOutputFeed() error = nil, want an unknown format error")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("wrote %q for an unknown format", buf.String())
+	}
+}
+
+func TestHandlerPublishArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "usage"},
+		{[]string{"json", "--link", "https://gator.example/"}, "unknown output feed format"},
+		{[]string{"rss"}, "needs --link"},
+		{[]string{"rss", "--output", "out.xml"}, "needs --link"},
+		{[]string{"rss", "--link"}, "no value provided for --link"},
+		{[]string{"atom", "--link=/timeline.xml"}, "absolute http(s) url"},
+		{[]string{"rss", "--link", "ftp://gator.example/"}, "absolute http(s) url"},
+		{[]string{"rss", "--link", "https://gator.example/", "extra"}, "usage"},
+	}
+	for _, tt := range tests {
+		err := HandlerPublish(&State{}, Command{Name: "publish", Args: tt.args}, database.User{})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("HandlerPublish(%q) error = %v, want it to mention %q", tt.args, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gabeportillo51/blog_aggregator/internal/database"
+)
+
+// a single permanent redirect can be a misconfigured server, so only move
+// the feed once the same target has come back this many fetches in a row
+const permanentRedirectThreshold = 3
+
+func trackRedirect(ctx context.Context, s *State, feed database.Feed, result *FetchResult) error {
+	target := ""
+	if result != nil {
+		target = result.RedirectURL
+	}
+	if target == "" {
+		if !feed.RedirectUrl.Valid {
+			return nil
+		}
+		return s.Db.RecordFeedRedirect(ctx, database.RecordFeedRedirectParams{
+			UpdatedAt: time.Now(),
+			ID:        feed.ID,
+		})
+	}
+	count := int32(1)
+	if feed.RedirectUrl.String == target {
+		count = feed.RedirectCount + 1
+	}
+	if count < permanentRedirectThreshold {
+		return s.Db.RecordFeedRedirect(ctx, database.RecordFeedRedirectParams{
+			UpdatedAt:     time.Now(),
+			RedirectUrl:   sql.NullString{String: target, Valid: true},
+			RedirectCount: count,
+			ID:            feed.ID,
+		})
+	}
+	return moveFeed(ctx, s, feed, target)
+}
+
+func moveFeed(ctx context.Context, s *State, feed database.Feed, target string) error {
+	existing, err := s.Db.GetFeed(ctx, target)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = s.Db.UpdateFeedURL(ctx, database.UpdateFeedURLParams{
+			UpdatedAt: time.Now(),
+			Url:       target,
+			ID:        feed.ID,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Feed '%s' moved permanently from %s to %s\n", feed.Name, feed.Url, target)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	// the new url is already a feed of its own, so fold this one into it;
+	// followers of both keep their existing follow
+	tx, err := s.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	q := s.Db.WithTx(tx)
+	err = q.MoveFeedFollows(ctx, database.MoveFeedFollowsParams{
+		UpdatedAt: time.Now(),
+		NewFeedID: existing.ID,
+		OldFeedID: feed.ID,
+	})
+	if err != nil {
+		return err
+	}
+	moved, err := q.MovePosts(ctx, database.MovePostsParams{
+		NewFeedID: existing.ID,
+		OldFeedID: feed.ID,
+	})
+	if err != nil {
+		return err
+	}
+	// what's left are posts the other feed already has; carry their read
+	// and saved state over before the delete cascades it away
+	err = q.MovePostReads(ctx, database.MovePostReadsParams{
+		NewFeedID: existing.ID,
+		OldFeedID: feed.ID,
+	})
+	if err != nil {
+		return err
+	}
+	err = q.MoveSavedPosts(ctx, database.MoveSavedPostsParams{
+		NewFeedID: existing.ID,
+		OldFeedID: feed.ID,
+	})
+	if err != nil {
+		return err
+	}
+	err = q.MoveFeedFetches(ctx, database.MoveFeedFetchesParams{
+		NewFeedID: existing.ID,
+		OldFeedID: feed.ID,
+	})
+	if err != nil {
+		return err
+	}
+	err = q.DeleteFeed(ctx, feed.ID)
+	if err != nil {
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Feed '%s' moved permanently to %s and was merged into '%s' (%d post(s) moved)\n", feed.Name, target, existing.Name, moved)
+	return nil
+}
+
+func deactivateGoneFeed(ctx context.Context, s *State, feed database.Feed) {
+	err := s.Db.DeactivateFeed(ctx, database.DeactivateFeedParams{
+		UpdatedAt:  time.Now(),
+		LastError:  sql.NullString{String: "feed is gone (410), no longer fetching it", Valid: true},
+		ID:         feed.ID,
+		ClaimToken: feed.ClaimToken,
+	})
+	if err != nil {
+		fmt.Printf("Error deactivating feed '%s': %v\n", feed.Name, err)
+		return
+	}
+	fmt.Printf("Feed '%s' is gone and has been deactivated\n", feed.Name)
+	err = s.Db.CreateFeedNotices(ctx, database.CreateFeedNoticesParams{
+		CreatedAt: time.Now(),
+		Message:   fmt.Sprintf("Feed '%s' (%s) no longer exists and will not be updated again; you may want to unfollow it", feed.Name, feed.Url),
+		FeedID:    feed.ID,
+	})
+	if err != nil {
+		fmt.Printf("Error notifying followers of feed '%s': %v\n", feed.Name, err)
+	}
+}
+
+// notices go to stderr so they never end up inside json or csv output
+func printFeedNotices(ctx context.Context, s *State, user database.User) error {
+	notices, err := s.Db.TakeFeedNoticesForUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	for _, notice := range notices {
+		fmt.Fprintf(os.Stderr, "Notice: %s\n", notice)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+	FormatCSV   = "csv"
+)
+
+const maxTableCellWidth = 60
+
+// Table rows hold typed values so json output keeps numbers, booleans and
+// nulls; nil is an empty cell in table and csv output
+type Table struct {
+	Columns []string
+	Rows    [][]any
+	Empty   string
+}
+
+func ExtractFormat(args []string) (string, []string, error) {
+	format := FormatTable
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--format":
+			if i+1 >= len(args) {
+				return "", nil, fmt.Errorf("no value provided for --format")
+			}
+			format = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "--format="):
+			format = strings.TrimPrefix(arg, "--format=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	switch format {
+	case FormatTable, FormatJSON, FormatCSV:
+		return format, rest, nil
+	}
+	return "", nil, fmt.Errorf("unknown output format '%s' (expected table, json or csv)", format)
+}
+
+func (s *State) Render(table Table) error {
+	return renderTable(os.Stdout, s.Format, table)
+}
+
+func renderTable(w io.Writer, format string, table Table) error {
+	switch format {
+	case FormatJSON:
+		return renderJSON(w, table)
+	case FormatCSV:
+		return renderCSV(w, table)
+	}
+	if len(table.Rows) == 0 && table.Empty != "" {
+		_, err := fmt.Fprintln(w, table.Empty)
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	headers := make([]string, len(table.Columns))
+	for i, column := range table.Columns {
+		headers[i] = strings.ToUpper(strings.ReplaceAll(column, "_", " "))
+	}
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	for _, row := range table.Rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = tableCell(cellString(cell))
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+// table cells have to stay on one line, so collapse whitespace and cut long values
+func tableCell(value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	runes := []rune(value)
+	if len(runes) > maxTableCellWidth {
+		return string(runes[:maxTableCellWidth-3]) + "..."
+	}
+	return value
+}
+
+func cellString(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case time.Time:
+		return value.Format(time.RFC3339)
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// nullString keeps missing values as null in json output
+func nullString(value sql.NullString) any {
+	if !value.Valid {
+		return nil
+	}
+	return value.String
+}
+
+func nullTime(value sql.NullTime) any {
+	if !value.Valid {
+		return nil
+	}
+	return value.Time
+}
+
+func renderJSON(w io.Writer, table Table) error {
+	rows := make([]map[string]any, 0, len(table.Rows))
+	for _, row := range table.Rows {
+		object := make(map[string]any, len(table.Columns))
+		for i, column := range table.Columns {
+			object[column] = row[i]
+		}
+		rows = append(rows, object)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rows)
+}
+
+func renderCSV(w io.Writer, table Table) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write(table.Columns)
+	if err != nil {
+		return err
+	}
+	for _, row := range table.Rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = cellString(cell)
+		}
+		err = writer.Write(cells)
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,197 @@
+package config
+
+import (
+	"bytes"
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExtractFormat(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantFormat string
+		wantRest   []string
+		wantErr    bool
+	}{
+		{name: "no args", args: nil, wantFormat: FormatTable},
+		{name: "no flag", args: []string{"5"}, wantFormat: FormatTable, wantRest: []string{"5"}},
+		{name: "separate value", args: []string{"--format", "json"}, wantFormat: FormatJSON},
+		{name: "joined value", args: []string{"--format=csv"}, wantFormat: FormatCSV},
+		{name: "explicit table", args: []string{"--format=table", "10"}, wantFormat: FormatTable, wantRest: []string{"10"}},
+		{name: "keeps other args in order", args: []string{"a", "--format", "json", "b", "--unread"}, wantFormat: FormatJSON, wantRest: []string{"a", "b", "--unread"}},
+		{name: "last flag wins", args: []string{"--format=csv", "--format", "json"}, wantFormat: FormatJSON},
+		{name: "similar flag passes through", args: []string{"--formats=json"}, wantFormat: FormatTable, wantRest: []string{"--formats=json"}},
+		{name: "missing value", args: []string{"tech", "--format"}, wantErr: true},
+		{name: "empty joined value", args: []string{"--format="}, wantErr: true},
+		{name: "flag as value", args: []string{"--format", "--format"}, wantErr: true},
+		{name: "unknown format", args: []string{"--format", "xml"}, wantErr: true},
+		{name: "case sensitive", args: []string{"--format=JSON"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, rest, err := ExtractFormat(tt.args)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ExtractFormat(%q) = %q, %q, want an error", tt.args, format, rest)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExtractFormat(%q) error = %v", tt.args, err)
+			}
+			if format != tt.wantFormat || !reflect.DeepEqual(rest, tt.wantRest) {
+				t.Errorf("ExtractFormat(%q) = %q, %q, want %q, %q", tt.args, format, rest, tt.wantFormat, tt.wantRest)
+			}
+		})
+	}
+}
+
+func testTable() Table {
+	when := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	return Table{
+		Columns: []string{"name", "last_fetched_at", "active", "score", "site_url"},
+		Rows: [][]any{
+			{"Example, Inc.", when, true, 0.25, "https://example.com"},
+			{"Never \"fetched\"", nil, false, int64(3), nil},
+		},
+		Empty: "No feeds found",
+	}
+}
+
+func TestRenderTable(t *testing.T) {
+	var buf bytes.Buffer
+	err := renderTable(&buf, FormatTable, testTable())
+	if err != nil {
+		t.Fatalf("renderTable() error = %v", err)
+	}
+	want := "" +
+		"NAME             LAST FETCHED AT       ACTIVE  SCORE  SITE URL\n" +
+		"Example, Inc.    2024-05-06T07:08:09Z  true    0.25   https://example.com\n" +
+		"Never \"fetched\"                        false   3      \n"
+	if buf.String() != want {
+		t.Errorf("renderTable() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestRenderTableCells(t *testing.T) {
+	long := strings.Repeat("x", maxTableCellWidth+10)
+	table := Table{
+		Columns: []string{"title"},
+		Rows:    [][]any{{"two\nlines\tand   spaces"}, {long}, {strings.Repeat("é", maxTableCellWidth)}},
+	}
+	var buf bytes.Buffer
+	err := renderTable(&buf, FormatTable, table)
+	if err != nil {
+		t.Fatalf("renderTable() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("renderTable() wrote %d lines, want a header and three rows:\n%s", len(lines), buf.String())
+	}
+	if strings.TrimSpace(lines[1]) != "two lines and spaces" {
+		t.Errorf("row = %q, want whitespace collapsed onto one line", lines[1])
+	}
+	if want := long[:maxTableCellWidth-3] + "..."; strings.TrimSpace(lines[2]) != want {
+		t.Errorf("row = %q, want it cut to %d characters", lines[2], maxTableCellWidth)
+	}
+	if strings.TrimSpace(lines[3]) != strings.Repeat("é", maxTableCellWidth) {
+		t.Errorf("row = %q, want a value at the limit left whole", lines[3])
+	}
+}
+
+func TestRenderTableEmpty(t *testing.T) {
+	table := testTable()
+	table.Rows = nil
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{FormatTable, "No feeds found\n"},
+		{FormatJSON, "[]\n"},
+		{FormatCSV, "name,last_fetched_at,active,score,site_url\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		err := renderTable(&buf, tt.format, table)
+		if err != nil {
+			t.Fatalf("renderTable(%s) error = %v", tt.format, err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("renderTable(%s) = %q, want %q", tt.format, buf.String(), tt.want)
+		}
+	}
+
+	// without an empty message the headers are still printed
+	table.Empty = ""
+	var buf bytes.Buffer
+	err := renderTable(&buf, FormatTable, table)
+	if err != nil {
+		t.Fatalf("renderTable() error = %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "NAME") {
+		t.Errorf("renderTable() = %q, want the header row", buf.String())
+	}
+}
+
+func TestRenderJSON(t *testing.T) {
+	var buf bytes.Buffer
+	err := renderJSON(&buf, testTable())
+	if err != nil {
+		t.Fatalf("renderJSON() error = %v", err)
+	}
+	want := `[
+  {
+    "active": true,
+    "last_fetched_at": "2024-05-06T07:08:09Z",
+    "name": "Example, Inc.",
+    "score": 0.25,
+    "site_url": "https://example.com"
+  },
+  {
+    "active": false,
+    "last_fetched_at": null,
+    "name": "Never \"fetched\"",
+    "score": 3,
+    "site_url": null
+  }
+]
+`
+	if buf.String() != want {
+		t.Errorf("renderJSON() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestRenderCSV(t *testing.T) {
+	var buf bytes.Buffer
+	err := renderCSV(&buf, testTable())
+	if err != nil {
+		t.Fatalf("renderCSV() error = %v", err)
+	}
+	want := "" +
+		"name,last_fetched_at,active,score,site_url\n" +
+		"\"Example, Inc.\",2024-05-06T07:08:09Z,true,0.25,https://example.com\n" +
+		"\"Never \"\"fetched\"\"\",,false,3,\n"
+	if buf.String() != want {
+		t.Errorf("renderCSV() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestNullValues(t *testing.T) {
+	when := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	if got := nullString(sql.NullString{}); got != nil {
+		t.Errorf("nullString(invalid) = %v, want nil", got)
+	}
+	if got := nullString(sql.NullString{String: "", Valid: true}); got != "" {
+		t.Errorf("nullString(empty) = %v, want an empty string", got)
+	}
+	if got := nullTime(sql.NullTime{}); got != nil {
+		t.Errorf("nullTime(invalid) = %v, want nil", got)
+	}
+	if got := nullTime(sql.NullTime{Time: when, Valid: true}); got != when {
+		t.Errorf("nullTime(valid) = %v, want %v", got, when)
+	}
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gabeportillo51/blog_aggregator/internal/database"
+)
+
+const (
+	defaultMinFetchInterval = 15 * time.Minute
+	defaultMaxFetchInterval = 24 * time.Hour
+	schedulePostSample      = 10
+)
+
+func (c Config) FetchIntervals() (time.Duration, time.Duration, error) {
+	minInterval, maxInterval := defaultMinFetchInterval, defaultMaxFetchInterval
+	var err error
+	if c.MinFetchInterval != "" {
+		minInterval, err = time.ParseDuration(c.MinFetchInterval)
+		if err != nil || minInterval <= 0 {
+			return defaultMinFetchInterval, defaultMaxFetchInterval, fmt.Errorf("invalid min_fetch_interval '%s'", c.MinFetchInterval)
+		}
+	}
+	if c.MaxFetchInterval != "" {
+		maxInterval, err = time.ParseDuration(c.MaxFetchInterval)
+		if err != nil || maxInterval <= 0 {
+			return defaultMinFetchInterval, defaultMaxFetchInterval, fmt.Errorf("invalid max_fetch_interval '%s'", c.MaxFetchInterval)
+		}
+	}
+	if minInterval > maxInterval {
+		return defaultMinFetchInterval, defaultMaxFetchInterval, fmt.Errorf("min_fetch_interval %v is longer than max_fetch_interval %v", minInterval, maxInterval)
+	}
+	return minInterval, maxInterval, nil
+}
+
+type scheduleHints struct {
+	TTL       time.Duration
+	SkipHours []int32
+	SkipDays  []string
+}
+
+func storedHints(feed database.Feed) scheduleHints {
+	hints := scheduleHints{
+		SkipHours: feed.SkipHours,
+		SkipDays:  feed.SkipDays,
+	}
+	if feed.TtlMinutes.Valid {
+		hints.TTL = time.Duration(feed.TtlMinutes.Int32) * time.Minute
+	}
+	return hints
+}
+
+func parsedHints(parsed *ParsedFeed) scheduleHints {
+	hints := scheduleHints{
+		TTL:       time.Duration(parsed.TTL) * time.Minute,
+		SkipHours: []int32{},
+		SkipDays:  []string{},
+	}
+	for _, hour := range parsed.SkipHours {
+		hints.SkipHours = append(hints.SkipHours, int32(hour))
+	}
+	hints.SkipDays = append(hints.SkipDays, parsed.SkipDays...)
+	return hints
+}
+
+func (h scheduleHints) equal(other scheduleHints) bool {
+	return h.TTL == other.TTL && slices.Equal(h.SkipHours, other.SkipHours) && slices.Equal(h.SkipDays, other.SkipDays)
+}
+
+func updateScheduleHints(ctx context.Context, s *State, feed database.Feed, hints scheduleHints) error {
+	return s.Db.UpdateFeedScheduleHints(ctx, database.UpdateFeedScheduleHintsParams{
+		UpdatedAt: time.Now(),
+		TtlMinutes: sql.NullInt32{
+			Int32: int32(hints.TTL / time.Minute),
+			Valid: hints.TTL > 0,
+		},
+		SkipHours: hints.SkipHours,
+		SkipDays:  hints.SkipDays,
+		ID:        feed.ID,
+	})
+}
+
+// posting frequency sets the pace: poll about twice per expected post, and
+// slow down for feeds that have gone quiet for longer than they usually do
+func fetchInterval(now time.Time, postTimes []time.Time, hints scheduleHints, minInterval, maxInterval time.Duration) time.Duration {
+	interval := maxInterval
+	if len(postTimes) > 0 {
+		quiet := now.Sub(postTimes[0])
+		average := quiet
+		if len(postTimes) > 1 {
+			average = postTimes[0].Sub(postTimes[len(postTimes)-1]) / time.Duration(len(postTimes)-1)
+		}
+		interval = max(average, quiet) / 2
+	}
+	if hints.TTL > interval {
+		interval = hints.TTL
+	}
+	return min(max(interval, minInterval), maxInterval)
+}
+
+// skipHours are in GMT, and both hints only ever push the next fetch later
+func nextFetchTime(now time.Time, interval time.Duration, hints scheduleHints) time.Time {
+	next := now.Add(interval)
+	for i := 0; i < 7*24; i++ {
+		utc := next.UTC()
+		skipDay := slices.ContainsFunc(hints.SkipDays, func(day string) bool {
+			return strings.EqualFold(day, utc.Weekday().String())
+		})
+		if !skipDay && !slices.Contains(hints.SkipHours, int32(utc.Hour())) {
+			break
+		}
+		next = utc.Truncate(time.Hour).Add(time.Hour)
+	}
+	return next.In(now.Location())
+}
+
+func scheduleNextFetch(ctx context.Context, s *State, feed database.Feed, hints scheduleHints) time.Time {
+	now := time.Now()
+	minInterval, maxInterval, _ := s.Cfg.FetchIntervals()
+	postTimes, err := s.Db.GetRecentPostTimes(ctx, database.GetRecentPostTimesParams{
+		FeedID: feed.ID,
+		Limit:  schedulePostSample,
+	})
+	if err != nil {
+		fmt.Printf("Error getting recent posts for feed '%s': %v\n", feed.Name, err)
+		return now.Add(minInterval)
+	}
+	return nextFetchTime(now, fetchInterval(now, postTimes, hints, minInterval, maxInterval), hints)
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFetchInterval(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	ago := func(durations ...time.Duration) []time.Time {
+		var times []time.Time
+		for _, d := range durations {
+			times = append(times, now.Add(-d))
+		}
+		return times
+	}
+	tests := []struct {
+		name      string
+		postTimes []time.Time
+		hints     scheduleHints
+		want      time.Duration
+	}{
+		{"no posts", nil, scheduleHints{}, 24 * time.Hour},
+		{"no posts with a ttl", nil, scheduleHints{TTL: time.Hour}, 24 * time.Hour},
+		{"single post", ago(10 * time.Hour), scheduleHints{}, 5 * time.Hour},
+		{"hourly posts", ago(30*time.Minute, 90*time.Minute, 150*time.Minute), scheduleHints{}, 30 * time.Minute},
+		{"gone quiet", ago(10*time.Hour, 11*time.Hour, 12*time.Hour), scheduleHints{}, 5 * time.Hour},
+		{"faster than the minimum", ago(time.Minute, 2*time.Minute), scheduleHints{}, 15 * time.Minute},
+		{"slower than the maximum", ago(100 * 24 * time.Hour), scheduleHints{}, 24 * time.Hour},
+		{"ttl slows it down", ago(30*time.Minute, 90*time.Minute), scheduleHints{TTL: 2 * time.Hour}, 2 * time.Hour},
+		{"ttl never speeds it up", ago(10 * time.Hour), scheduleHints{TTL: time.Hour}, 5 * time.Hour},
+		{"ttl past the maximum", ago(30 * time.Minute), scheduleHints{TTL: 48 * time.Hour}, 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fetchInterval(now, tt.postTimes, tt.hints, 15*time.Minute, 24*time.Hour)
+			if got != tt.want {
+				t.Errorf("fetchInterval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextFetchTime(t *testing.T) {
+	// a monday evening, gmt
+	now := time.Date(2024, 1, 1, 22, 30, 0, 0, time.UTC)
+	eastern := time.FixedZone("EST", -5*60*60)
+	tests := []struct {
+		name     string
+		now      time.Time
+		interval time.Duration
+		hints    scheduleHints
+		want     time.Time
+	}{
+		{
+			name:     "no hints",
+			now:      now,
+			interval: time.Hour,
+			want:     time.Date(2024, 1, 1, 23, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "outside the skipped hours",
+			now:      now,
+			interval: time.Hour,
+			hints:    scheduleHints{SkipHours: []int32{2, 3}},
+			want:     time.Date(2024, 1, 1, 23, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "skipped hours wrap past midnight",
+			now:      now,
+			interval: time.Hour,
+			hints:    scheduleHints{SkipHours: []int32{23, 0, 1}},
+			want:     time.Date(2024, 1, 2, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "skipped day",
+			now:      now,
+			interval: 2 * time.Hour,
+			hints:    scheduleHints{SkipDays: []string{"tuesday"}},
+			want:     time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "skipped day and hour",
+			now:      now,
+			interval: 2 * time.Hour,
+			hints:    scheduleHints{SkipHours: []int32{0}, SkipDays: []string{"Tuesday"}},
+			want:     time.Date(2024, 1, 3, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "skipped hours are gmt",
+			now:      now.In(eastern),
+			interval: time.Hour,
+			hints:    scheduleHints{SkipHours: []int32{23}},
+			want:     time.Date(2024, 1, 1, 19, 0, 0, 0, eastern),
+		},
+		{
+			name:     "every hour skipped gives up after a week",
+			now:      now,
+			interval: time.Hour,
+			hints:    scheduleHints{SkipDays: []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}},
+			want:     time.Date(2024, 1, 8, 23, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nextFetchTime(tt.now, tt.interval, tt.hints)
+			if !got.Equal(tt.want) {
+				t.Errorf("nextFetchTime() = %v, want %v", got, tt.want)
+			}
+			if got.Location() != tt.now.Location() {
+				t.Errorf("nextFetchTime() location = %v, want %v", got.Location(), tt.now.Location())
+			}
+		})
+	}
+}
+
+func TestParsedHints(t *testing.T) {
+	data := `<rss version="2.0">
+  <channel>
+    <title>Hinted</title>
+    <ttl> 60 </ttl>
+    <skipHours><hour>0</hour><hour>24</hour><hour>25</hour><hour>noon</hour><hour>13</hour></skipHours>
+    <skipDays><day>Saturday</day><day> </day><day>Sunday</day></skipDays>
+  </channel>
+</rss>`
+	parsed, err := parseFeed("application/rss+xml", []byte(data))
+	if err != nil {
+		t.Fatalf("parseFeed() error = %v", err)
+	}
+	want := scheduleHints{
+		TTL:       time.Hour,
+		SkipHours: []int32{0, 0, 13},
+		SkipDays:  []string{"Saturday", "Sunday"},
+	}
+	if got := parsedHints(parsed); !got.equal(want) {
+		t.Errorf("parsedHints() = %+v, want %+v", got, want)
+	}
+}
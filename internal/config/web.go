@@ -0,0 +1,409 @@
+package config
+
+import (
+	"bytes"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gabeportillo51/blog_aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+const (
+	webSessionCookie   = "gator_session"
+	webSessionLength   = 30 * 24 * time.Hour
+	webPageSize        = 20
+	passwordIterations = 600000
+)
+
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+type webServer struct {
+	state     *State
+	templates map[string]*template.Template
+}
+
+type webPage struct {
+	Title      string
+	User       *database.User
+	Name       string
+	Error      string
+	Follows    []database.GetFeedFollowsForUserRow
+	Feed       *database.Feed
+	Posts      []database.GetPostsForUserRow
+	UnreadOnly bool
+	Return     string
+	PrevPage   string
+	NextPage   string
+}
+
+func HandlerWeb(s *State, cmd Command) error {
+	if len(cmd.Args) > 1 {
+		return errors.New("error: incorrect number of arguments provided to 'web' command")
+	}
+	addr := "127.0.0.1:8081"
+	if len(cmd.Args) == 1 {
+		addr = cmd.Args[0]
+	}
+	web, err := newWebServer(s)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           web.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	fmt.Printf("Serving the gator web reader on %s\n", addr)
+	return server.ListenAndServe()
+}
+
+func newWebServer(s *State) (*webServer, error) {
+	funcs := template.FuncMap{
+		"summary": summarize,
+	}
+	web := &webServer{
+		state:     s,
+		templates: make(map[string]*template.Template),
+	}
+	for _, page := range []string{"login", "timeline", "feeds"} {
+		tmpl, err := template.New(page).Funcs(funcs).ParseFS(templateFS, "templates/layout.html", "templates/"+page+".html")
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %s template: %w", page, err)
+		}
+		web.templates[page] = tmpl
+	}
+	return web, nil
+}
+
+func (web *webServer) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /login", web.handleLoginPage)
+	mux.HandleFunc("POST /login", web.handleLogin)
+	mux.HandleFunc("POST /logout", web.handleLogout)
+	mux.HandleFunc("GET /{$}", web.withUser(web.handleTimeline))
+	mux.HandleFunc("POST /read", web.withUser(web.handleMarkAllRead))
+	mux.HandleFunc("GET /feeds", web.withUser(web.handleFeeds))
+	mux.HandleFunc("GET /feeds/{id}", web.withUser(web.handleFeed))
+	mux.HandleFunc("POST /feeds/{id}/read", web.withUser(web.handleMarkFeedRead))
+	mux.HandleFunc("POST /posts/{id}/read", web.withUser(web.handleMarkRead))
+	mux.HandleFunc("POST /posts/{id}/unread", web.withUser(web.handleMarkUnread))
+	return mux
+}
+
+func (web *webServer) withUser(handler func(http.ResponseWriter, *http.Request, database.User)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie(webSessionCookie)
+		if err != nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		user, err := web.state.Db.GetUserByWebSession(r.Context(), database.GetUserByWebSessionParams{
+			TokenHash: hashToken(cookie.Value),
+			ExpiresAt: time.Now(),
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		} else if err != nil {
+			http.Error(w, "database error", http.StatusInternalServerError)
+			return
+		}
+		handler(w, r, user)
+	}
+}
+
+// render buffers the page so a template error can still become a clean 500
+func (web *webServer) render(w http.ResponseWriter, status int, page string, data webPage) {
+	var buf bytes.Buffer
+	err := web.templates[page].ExecuteTemplate(&buf, "layout", data)
+	if err != nil {
+		http.Error(w, "error rendering page", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
+
+// descriptions are feed-supplied HTML, so only show their text
+func summarize(description string) string {
+	text := htmlTagPattern.ReplaceAllString(description, " ")
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) > 300 {
+		return string(runes[:300]) + "..."
+	}
+	return text
+}
+
+// only redirect back to local paths
+// returnPath only sends the browser back to a path on this site; browsers
+// read a backslash as a slash, so "/\evil.example" counts as another host
+func returnPath(r *http.Request, fallback string) string {
+	value := r.FormValue("return")
+	if !strings.HasPrefix(value, "/") || strings.HasPrefix(value, "//") || strings.Contains(value, "\\") {
+		return fallback
+	}
+	target, err := url.Parse(value)
+	if err != nil || target.Scheme != "" || target.Host != "" {
+		return fallback
+	}
+	return value
+}
+
+func hashPassword(password string) (string, error) {
+	salt := make([]byte, 16)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", err
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, passwordIterations, sha256.Size)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("pbkdf2-sha256$%d$%s$%s", passwordIterations, hex.EncodeToString(salt), hex.EncodeToString(key)), nil
+}
+
+func checkPassword(password, encoded string) bool {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 || parts[0] != "pbkdf2-sha256" {
+		return false
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false
+	}
+	salt, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	expected, err := hex.DecodeString(parts[3])
+	if err != nil || len(expected) != sha256.Size {
+		return false
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(expected))
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(key, expected) == 1
+}
+
+func (web *webServer) handleLoginPage(w http.ResponseWriter, r *http.Request) {
+	web.render(w, http.StatusOK, "login", webPage{Title: "Sign in"})
+}
+
+func (web *webServer) handleLogin(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("name")
+	user, err := web.state.Db.GetUser(r.Context(), name)
+	if err != nil || !user.PasswordHash.Valid || !checkPassword(r.FormValue("password"), user.PasswordHash.String) {
+		web.render(w, http.StatusUnauthorized, "login", webPage{
+			Title: "Sign in",
+			Name:  name,
+			Error: "Unknown user name or wrong password.",
+		})
+		return
+	}
+	token, err := newToken()
+	if err != nil {
+		http.Error(w, "error creating session", http.StatusInternalServerError)
+		return
+	}
+	err = web.state.Db.CreateWebSession(r.Context(), database.CreateWebSessionParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(webSessionLength),
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+	})
+	if err != nil {
+		http.Error(w, "error creating session", http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     webSessionCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(webSessionLength / time.Second),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (web *webServer) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(webSessionCookie); err == nil {
+		err = web.state.Db.DeleteWebSession(r.Context(), hashToken(cookie.Value))
+		if err != nil {
+			http.Error(w, "error ending session", http.StatusInternalServerError)
+			return
+		}
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     webSessionCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+func (web *webServer) postsPage(w http.ResponseWriter, r *http.Request, user database.User, feed *database.Feed, title string) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	unread_only := r.URL.Query().Get("all") == ""
+	params := database.GetPostsForUserParams{
+		UserID:     user.ID,
+		UnreadOnly: unread_only,
+		PostLimit:  webPageSize + 1,
+		PostOffset: int32((page - 1) * webPageSize),
+	}
+	if feed != nil {
+		params.FeedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+	posts, err := web.state.Db.GetPostsForUser(r.Context(), params)
+	if err != nil {
+		http.Error(w, "error getting posts", http.StatusInternalServerError)
+		return
+	}
+	data := webPage{
+		Title:      title,
+		User:       &user,
+		Feed:       feed,
+		UnreadOnly: unread_only,
+		Return:     r.URL.RequestURI(),
+	}
+	query := r.URL.Query()
+	if len(posts) > webPageSize {
+		posts = posts[:webPageSize]
+		query.Set("page", strconv.Itoa(page+1))
+		data.NextPage = "?" + query.Encode()
+	}
+	if page > 1 {
+		query.Set("page", strconv.Itoa(page-1))
+		data.PrevPage = "?" + query.Encode()
+	}
+	data.Posts = posts
+	web.render(w, http.StatusOK, "timeline", data)
+}
+
+func (web *webServer) handleTimeline(w http.ResponseWriter, r *http.Request, user database.User) {
+	web.postsPage(w, r, user, nil, "Timeline")
+}
+
+func (web *webServer) handleFeeds(w http.ResponseWriter, r *http.Request, user database.User) {
+	feed_follows, err := web.state.Db.GetFeedFollowsForUser(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, "error getting feeds", http.StatusInternalServerError)
+		return
+	}
+	web.render(w, http.StatusOK, "feeds", webPage{Title: "Feeds", User: &user, Follows: feed_follows})
+}
+
+func (web *webServer) feedFromPath(w http.ResponseWriter, r *http.Request) (*database.Feed, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.NotFound(w, r)
+		return nil, false
+	}
+	feed, err := web.state.Db.GetFeedFromID(r.Context(), id)
+	if err != nil {
+		http.NotFound(w, r)
+		return nil, false
+	}
+	return &feed, true
+}
+
+func (web *webServer) handleFeed(w http.ResponseWriter, r *http.Request, user database.User) {
+	feed, ok := web.feedFromPath(w, r)
+	if !ok {
+		return
+	}
+	web.postsPage(w, r, user, feed, feed.Name)
+}
+
+func (web *webServer) handleMarkFeedRead(w http.ResponseWriter, r *http.Request, user database.User) {
+	feed, ok := web.feedFromPath(w, r)
+	if !ok {
+		return
+	}
+	_, err := web.state.Db.MarkFeedPostsRead(r.Context(), database.MarkFeedPostsReadParams{
+		CreatedAt: time.Now(),
+		UserID:    user.ID,
+		FeedID:    feed.ID,
+	})
+	if err != nil {
+		http.Error(w, "error marking feed as read", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, returnPath(r, "/feeds/"+feed.ID.String()), http.StatusSeeOther)
+}
+
+func (web *webServer) handleMarkAllRead(w http.ResponseWriter, r *http.Request, user database.User) {
+	_, err := web.state.Db.MarkAllPostsRead(r.Context(), database.MarkAllPostsReadParams{
+		CreatedAt: time.Now(),
+		UserID:    user.ID,
+	})
+	if err != nil {
+		http.Error(w, "error marking posts as read", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, returnPath(r, "/"), http.StatusSeeOther)
+}
+
+func (web *webServer) handleMarkRead(w http.ResponseWriter, r *http.Request, user database.User) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	err = web.state.Db.MarkPostRead(r.Context(), database.MarkPostReadParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		PostID:    id,
+	})
+	if err != nil {
+		http.Error(w, "error marking post as read", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, returnPath(r, "/"), http.StatusSeeOther)
+}
+
+func (web *webServer) handleMarkUnread(w http.ResponseWriter, r *http.Request, user database.User) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	err = web.state.Db.MarkPostUnread(r.Context(), database.MarkPostUnreadParams{
+		UserID: user.ID,
+		PostID: id,
+	})
+	if err != nil {
+		http.Error(w, "error marking post as unread", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, returnPath(r, "/"), http.StatusSeeOther)
+}
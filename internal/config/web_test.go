@@ -0,0 +1,124 @@
+package config
+
+import (
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestReturnPath(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", "/fallback"},
+		{"/", "/"},
+		{"/feeds/123?page=2", "/feeds/123?page=2"},
+		{"/?unread=0#top", "/?unread=0#top"},
+		{"feeds", "/fallback"},
+		{"//evil.example", "/fallback"},
+		{"/\\evil.example", "/fallback"},
+		{"/\\/evil.example", "/fallback"},
+		{"https://evil.example/", "/fallback"},
+		{"javascript:alert(1)", "/fallback"},
+		{"/\t/evil.example", "/fallback"},
+		{"/%zz", "/fallback"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/read", strings.NewReader(url.Values{"return": {tt.value}}.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if got := returnPath(r, "/fallback"); got != tt.want {
+			t.Errorf("returnPath(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestLoginFailure(t *testing.T) {
+	_, queries := newFakeDB(t, nil)
+	web, err := newWebServer(&State{Db: queries})
+	if err != nil {
+		t.Fatalf("newWebServer() error = %v", err)
+	}
+	form := url.Values{"name": {"nobody"}, "password": {"secret"}}
+	r := httptest.NewRequest("POST", "/login", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	web.routes().ServeHTTP(w, r)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if got := w.Header().Get("Content-Type"); got != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want text/html", got)
+	}
+	if len(w.Result().Cookies()) != 0 {
+		t.Errorf("failed login set cookies %v", w.Result().Cookies())
+	}
+	if body := w.Body.String(); !strings.Contains(body, "wrong password") || !strings.Contains(body, `value="nobody"`) {
+		t.Errorf("body does not explain the failure:\n%s", body)
+	}
+}
+
+func TestHashPassword(t *testing.T) {
+	encoded, err := hashPassword("correct horse")
+	if err != nil {
+		t.Fatalf("hashPassword() error = %v", err)
+	}
+	if !strings.HasPrefix(encoded, fmt.Sprintf("pbkdf2-sha256$%d$", passwordIterations)) {
+		t.Errorf("hashPassword() = %q, want a pbkdf2-sha256 encoding", encoded)
+	}
+	if !checkPassword("correct horse", encoded) {
+		t.Errorf("checkPassword() rejected the password it was hashed from")
+	}
+	if checkPassword("correct horse ", encoded) {
+		t.Errorf("checkPassword() accepted a different password")
+	}
+	again, err := hashPassword("correct horse")
+	if err != nil {
+		t.Fatalf("hashPassword() error = %v", err)
+	}
+	if again == encoded {
+		t.Errorf("hashPassword() gave the same encoding twice, want a fresh salt")
+	}
+}
+
+func TestCheckPassword(t *testing.T) {
+	// stored hashes keep their own iteration count, so a cheap one is fine here
+	salt := []byte("0123456789abcdef")
+	key, err := pbkdf2.Key(sha256.New, "secret", salt, 10, 32)
+	if err != nil {
+		t.Fatalf("pbkdf2.Key() error = %v", err)
+	}
+	valid := fmt.Sprintf("pbkdf2-sha256$10$%s$%s", hex.EncodeToString(salt), hex.EncodeToString(key))
+	tests := []struct {
+		name     string
+		password string
+		encoded  string
+		want     bool
+	}{
+		{"matching", "secret", valid, true},
+		{"wrong password", "Secret", valid, false},
+		{"empty password", "", valid, false},
+		{"empty encoding", "secret", "", false},
+		{"other scheme", "secret", strings.Replace(valid, "pbkdf2-sha256", "bcrypt", 1), false},
+		{"bad iterations", "secret", strings.Replace(valid, "$10$", "$ten$", 1), false},
+		{"zero iterations", "secret", strings.Replace(valid, "$10$", "$0$", 1), false},
+		{"bad salt", "secret", fmt.Sprintf("pbkdf2-sha256$10$zz$%s", hex.EncodeToString(key)), false},
+		{"bad key", "secret", fmt.Sprintf("pbkdf2-sha256$10$%s$zz", hex.EncodeToString(salt)), false},
+		{"truncated key", "secret", valid[:len(valid)-2], false},
+		{"extra field", "secret", valid + "$00", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkPassword(tt.password, tt.encoded); got != tt.want {
+				t.Errorf("checkPassword(%q, %q) = %v, want %v", tt.password, tt.encoded, got, tt.want)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,17 +15,18 @@ import (
 
 const createFeedFollow = `-- name: CreateFeedFollow :one
 WITH inserted_feed_follow AS (
-    INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
+    INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, category)
     VALUES (
         $1,
         $2,
         $3,
         $4,
-        $5
+        $5,
+        $6
     )
-    RETURNING id, created_at, updated_at, user_id, feed_id
+    RETURNING id, created_at, updated_at, user_id, feed_id, category
 )
-SELECT inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id, feeds.name AS feed_name, users.name AS user_name
+SELECT inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id, inserted_feed_follow.category, feeds.name AS feed_name, users.name AS user_name
 FROM inserted_feed_follow
 INNER JOIN feeds ON inserted_feed_follow.feed_id = feeds.id
 INNER JOIN users ON inserted_feed_follow.user_id = users.id
@@ -36,6 +38,7 @@ type CreateFeedFollowParams struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	Category  sql.NullString
 }
 
 type CreateFeedFollowRow struct {
@@ -44,6 +47,7 @@ type CreateFeedFollowRow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	Category  sql.NullString
 	FeedName  string
 	UserName  string
 }
@@ -55,6 +59,7 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		arg.UpdatedAt,
 		arg.UserID,
 		arg.FeedID,
+		arg.Category,
 	)
 	var i CreateFeedFollowRow
 	err := row.Scan(
@@ -63,6 +68,7 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.FeedID,
+		&i.Category,
 		&i.FeedName,
 		&i.UserName,
 	)
@@ -85,7 +91,7 @@ func (q *Queries) DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowPara
 }
 
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
-SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, feeds.name AS feed_name, users.name AS user_name
+SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, feed_follows.category, feeds.name AS feed_name, feeds.url AS feed_url, feeds.site_url AS feed_site_url, users.name AS user_name
 FROM feed_follows
 INNER JOIN feeds ON feed_follows.feed_id = feeds.id
 INNER JOIN users ON feed_follows.user_id = users.id
@@ -93,13 +99,16 @@ WHERE feed_follows.user_id = $1
 `
 
 type GetFeedFollowsForUserRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.UUID
-	FeedID    uuid.UUID
-	FeedName  string
-	UserName  string
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	FeedID      uuid.UUID
+	Category    sql.NullString
+	FeedName    string
+	FeedUrl     string
+	FeedSiteUrl sql.NullString
+	UserName    string
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
@@ -117,7 +126,10 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 			&i.UpdatedAt,
 			&i.UserID,
 			&i.FeedID,
+			&i.Category,
 			&i.FeedName,
+			&i.FeedUrl,
+			&i.FeedSiteUrl,
 			&i.UserName,
 		); err != nil {
 			return nil, err
@@ -132,3 +144,145 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	}
 	return items, nil
 }
+
+const getSubscriptionsForUser = `-- name: GetSubscriptionsForUser :many
+SELECT feeds.numeric_id, feeds.name, feeds.url, feeds.site_url, feeds.last_success_at, feed_follows.category
+FROM feed_follows
+INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+ORDER BY feeds.numeric_id
+`
+
+type GetSubscriptionsForUserRow struct {
+	NumericID     int64
+	Name          string
+	Url           string
+	SiteUrl       sql.NullString
+	LastSuccessAt sql.NullTime
+	Category      sql.NullString
+}
+
+func (q *Queries) GetSubscriptionsForUser(ctx context.Context, userID uuid.UUID) ([]GetSubscriptionsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getSubscriptionsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetSubscriptionsForUserRow
+	for rows.Next() {
+		var i GetSubscriptionsForUserRow
+		if err := rows.Scan(
+			&i.NumericID,
+			&i.Name,
+			&i.Url,
+			&i.SiteUrl,
+			&i.LastSuccessAt,
+			&i.Category,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFeedFollowsPage = `-- name: ListFeedFollowsPage :many
+SELECT feed_follows.id, feed_follows.created_at, feed_follows.feed_id, feed_follows.category, feeds.name AS feed_name, feeds.url AS feed_url
+FROM feed_follows
+INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+ORDER BY feed_follows.created_at ASC, feed_follows.id ASC
+LIMIT $2 OFFSET $3
+`
+
+type ListFeedFollowsPageParams struct {
+	UserID uuid.UUID
+	Limit  int32
+	Offset int32
+}
+
+type ListFeedFollowsPageRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	FeedID    uuid.UUID
+	Category  sql.NullString
+	FeedName  string
+	FeedUrl   string
+}
+
+func (q *Queries) ListFeedFollowsPage(ctx context.Context, arg ListFeedFollowsPageParams) ([]ListFeedFollowsPageRow, error) {
+	rows, err := q.db.QueryContext(ctx, listFeedFollowsPage, arg.UserID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListFeedFollowsPageRow
+	for rows.Next() {
+		var i ListFeedFollowsPageRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.FeedID,
+			&i.Category,
+			&i.FeedName,
+			&i.FeedUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const moveFeedFollows = `-- name: MoveFeedFollows :exec
+UPDATE feed_follows
+SET updated_at = $1, feed_id = $2
+WHERE feed_id = $3
+AND user_id NOT IN (SELECT user_id FROM feed_follows WHERE feed_id = $2)
+`
+
+type MoveFeedFollowsParams struct {
+	UpdatedAt time.Time
+	NewFeedID uuid.UUID
+	OldFeedID uuid.UUID
+}
+
+func (q *Queries) MoveFeedFollows(ctx context.Context, arg MoveFeedFollowsParams) error {
+	_, err := q.db.ExecContext(ctx, moveFeedFollows, arg.UpdatedAt, arg.NewFeedID, arg.OldFeedID)
+	return err
+}
+
+const updateFeedFollowCategory = `-- name: UpdateFeedFollowCategory :exec
+UPDATE feed_follows
+SET updated_at = $1, category = $2
+WHERE user_id = $3 AND feed_id = $4
+`
+
+type UpdateFeedFollowCategoryParams struct {
+	UpdatedAt time.Time
+	Category  sql.NullString
+	UserID    uuid.UUID
+	FeedID    uuid.UUID
+}
+
+func (q *Queries) UpdateFeedFollowCategory(ctx context.Context, arg UpdateFeedFollowCategoryParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedFollowCategory,
+		arg.UpdatedAt,
+		arg.Category,
+		arg.UserID,
+		arg.FeedID,
+	)
+	return err
+}
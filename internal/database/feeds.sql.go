@@ -11,19 +11,99 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const claimFeedsToFetch = `-- name: ClaimFeedsToFetch :many
+UPDATE feeds
+SET updated_at = $1, last_fetched_at = $2, claimed_until = $3, claim_token = $4
+WHERE id IN (
+    SELECT id FROM feeds
+    WHERE active
+    AND (claimed_until IS NULL OR claimed_until <= $1)
+    AND (next_fetch_at IS NULL OR next_fetch_at <= $1)
+    AND (backoff_until IS NULL OR backoff_until <= $1)
+    ORDER BY next_fetch_at ASC NULLS FIRST, last_fetched_at ASC NULLS FIRST
+    LIMIT $5
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, claimed_until, claim_token, failure_count, last_error, backoff_until, last_success_at, site_url, numeric_id, next_fetch_at, ttl_minutes, skip_hours, skip_days, redirect_url, redirect_count, active
+`
+
+type ClaimFeedsToFetchParams struct {
+	UpdatedAt     time.Time
+	LastFetchedAt sql.NullTime
+	ClaimedUntil  sql.NullTime
+	ClaimToken    uuid.NullUUID
+	Limit         int32
+}
+
+func (q *Queries) ClaimFeedsToFetch(ctx context.Context, arg ClaimFeedsToFetchParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, claimFeedsToFetch,
+		arg.UpdatedAt,
+		arg.LastFetchedAt,
+		arg.ClaimedUntil,
+		arg.ClaimToken,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.ClaimedUntil,
+			&i.ClaimToken,
+			&i.FailureCount,
+			&i.LastError,
+			&i.BackoffUntil,
+			&i.LastSuccessAt,
+			&i.SiteUrl,
+			&i.NumericID,
+			&i.NextFetchAt,
+			&i.TtlMinutes,
+			pq.Array(&i.SkipHours),
+			pq.Array(&i.SkipDays),
+			&i.RedirectUrl,
+			&i.RedirectCount,
+			&i.Active,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createFeed = `-- name: CreateFeed :one
-INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, site_url)
 VALUES (
     $1,
     $2,
     $3,
     $4,
     $5,
-    $6
+    $6,
+    $7
 )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, claimed_until, claim_token, failure_count, last_error, backoff_until, last_success_at, site_url, numeric_id, next_fetch_at, ttl_minutes, skip_hours, skip_days, redirect_url, redirect_count, active
 `
 
 type CreateFeedParams struct {
@@ -33,6 +113,7 @@ type CreateFeedParams struct {
 	Name      string
 	Url       string
 	UserID    uuid.UUID
+	SiteUrl   sql.NullString
 }
 
 func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, error) {
@@ -43,6 +124,7 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		arg.Name,
 		arg.Url,
 		arg.UserID,
+		arg.SiteUrl,
 	)
 	var i Feed
 	err := row.Scan(
@@ -53,12 +135,62 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.ClaimedUntil,
+		&i.ClaimToken,
+		&i.FailureCount,
+		&i.LastError,
+		&i.BackoffUntil,
+		&i.LastSuccessAt,
+		&i.SiteUrl,
+		&i.NumericID,
+		&i.NextFetchAt,
+		&i.TtlMinutes,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
+		&i.RedirectUrl,
+		&i.RedirectCount,
+		&i.Active,
 	)
 	return i, err
 }
 
+const deactivateFeed = `-- name: DeactivateFeed :exec
+UPDATE feeds
+SET updated_at = $1, active = FALSE, last_error = $2, backoff_until = NULL, next_fetch_at = NULL, claimed_until = NULL, claim_token = NULL
+WHERE id = $3 AND claim_token = $4
+`
+
+type DeactivateFeedParams struct {
+	UpdatedAt  time.Time
+	LastError  sql.NullString
+	ID         uuid.UUID
+	ClaimToken uuid.NullUUID
+}
+
+func (q *Queries) DeactivateFeed(ctx context.Context, arg DeactivateFeedParams) error {
+	_, err := q.db.ExecContext(ctx, deactivateFeed,
+		arg.UpdatedAt,
+		arg.LastError,
+		arg.ID,
+		arg.ClaimToken,
+	)
+	return err
+}
+
+const deleteFeed = `-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeed, id)
+	return err
+}
+
 const getFeed = `-- name: GetFeed :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, claimed_until, claim_token, failure_count, last_error, backoff_until, last_success_at, site_url, numeric_id, next_fetch_at, ttl_minutes, skip_hours, skip_days, redirect_url, redirect_count, active FROM feeds
 WHERE url = $1
 `
 
@@ -73,12 +205,29 @@ func (q *Queries) GetFeed(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.ClaimedUntil,
+		&i.ClaimToken,
+		&i.FailureCount,
+		&i.LastError,
+		&i.BackoffUntil,
+		&i.LastSuccessAt,
+		&i.SiteUrl,
+		&i.NumericID,
+		&i.NextFetchAt,
+		&i.TtlMinutes,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
+		&i.RedirectUrl,
+		&i.RedirectCount,
+		&i.Active,
 	)
 	return i, err
 }
 
 const getFeedFromID = `-- name: GetFeedFromID :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, claimed_until, claim_token, failure_count, last_error, backoff_until, last_success_at, site_url, numeric_id, next_fetch_at, ttl_minutes, skip_hours, skip_days, redirect_url, redirect_count, active FROM feeds
 WHERE id = $1
 `
 
@@ -93,27 +242,23 @@ func (q *Queries) GetFeedFromID(ctx context.Context, id uuid.UUID) (Feed, error)
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
-	)
-	return i, err
-}
-
-const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
-ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1
-`
-
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
-	var i Feed
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Name,
-		&i.Url,
-		&i.UserID,
-		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.ClaimedUntil,
+		&i.ClaimToken,
+		&i.FailureCount,
+		&i.LastError,
+		&i.BackoffUntil,
+		&i.LastSuccessAt,
+		&i.SiteUrl,
+		&i.NumericID,
+		&i.NextFetchAt,
+		&i.TtlMinutes,
+		pq.Array(&i.SkipHours),
+		pq.Array(&i.SkipDays),
+		&i.RedirectUrl,
+		&i.RedirectCount,
+		&i.Active,
 	)
 	return i, err
 }
@@ -153,19 +298,270 @@ func (q *Queries) ListFeeds(ctx context.Context) ([]ListFeedsRow, error) {
 	return items, nil
 }
 
-const markFeedFetched = `-- name: MarkFeedFetched :exec
-UPDATE feeds
-SET updated_at = $1, last_fetched_at = $2
-WHERE id = $3
+const listFeedsPage = `-- name: ListFeedsPage :many
+SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.site_url, feeds.user_id, feeds.last_fetched_at, feeds.last_success_at, users.name AS user_name
+FROM feeds
+LEFT JOIN users ON feeds.user_id = users.id
+ORDER BY feeds.created_at ASC
+LIMIT $1 OFFSET $2
 `
 
-type MarkFeedFetchedParams struct {
+type ListFeedsPageParams struct {
+	Limit  int32
+	Offset int32
+}
+
+type ListFeedsPageRow struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
 	UpdatedAt     time.Time
+	Name          string
+	Url           string
+	SiteUrl       sql.NullString
+	UserID        uuid.UUID
 	LastFetchedAt sql.NullTime
+	LastSuccessAt sql.NullTime
+	UserName      sql.NullString
+}
+
+func (q *Queries) ListFeedsPage(ctx context.Context, arg ListFeedsPageParams) ([]ListFeedsPageRow, error) {
+	rows, err := q.db.QueryContext(ctx, listFeedsPage, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListFeedsPageRow
+	for rows.Next() {
+		var i ListFeedsPageRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.SiteUrl,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.LastSuccessAt,
+			&i.UserName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const postponeFeed = `-- name: PostponeFeed :exec
+UPDATE feeds
+SET updated_at = $1, next_fetch_at = $2, claimed_until = NULL, claim_token = NULL
+WHERE id = $3 AND claim_token = $4
+`
+
+type PostponeFeedParams struct {
+	UpdatedAt   time.Time
+	NextFetchAt sql.NullTime
+	ID          uuid.UUID
+	ClaimToken  uuid.NullUUID
+}
+
+func (q *Queries) PostponeFeed(ctx context.Context, arg PostponeFeedParams) error {
+	_, err := q.db.ExecContext(ctx, postponeFeed,
+		arg.UpdatedAt,
+		arg.NextFetchAt,
+		arg.ID,
+		arg.ClaimToken,
+	)
+	return err
+}
+
+const recordFeedFailure = `-- name: RecordFeedFailure :exec
+UPDATE feeds
+SET updated_at = $1, failure_count = failure_count + 1, last_error = $2, backoff_until = $3, claimed_until = NULL, claim_token = NULL
+WHERE id = $4 AND claim_token = $5
+`
+
+type RecordFeedFailureParams struct {
+	UpdatedAt    time.Time
+	LastError    sql.NullString
+	BackoffUntil sql.NullTime
+	ID           uuid.UUID
+	ClaimToken   uuid.NullUUID
+}
+
+func (q *Queries) RecordFeedFailure(ctx context.Context, arg RecordFeedFailureParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedFailure,
+		arg.UpdatedAt,
+		arg.LastError,
+		arg.BackoffUntil,
+		arg.ID,
+		arg.ClaimToken,
+	)
+	return err
+}
+
+const recordFeedRedirect = `-- name: RecordFeedRedirect :exec
+UPDATE feeds
+SET updated_at = $1, redirect_url = $2, redirect_count = $3
+WHERE id = $4
+`
+
+type RecordFeedRedirectParams struct {
+	UpdatedAt     time.Time
+	RedirectUrl   sql.NullString
+	RedirectCount int32
 	ID            uuid.UUID
 }
 
-func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams) error {
-	_, err := q.db.ExecContext(ctx, markFeedFetched, arg.UpdatedAt, arg.LastFetchedAt, arg.ID)
+func (q *Queries) RecordFeedRedirect(ctx context.Context, arg RecordFeedRedirectParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedRedirect,
+		arg.UpdatedAt,
+		arg.RedirectUrl,
+		arg.RedirectCount,
+		arg.ID,
+	)
+	return err
+}
+
+const recordFeedSuccess = `-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET updated_at = $1, last_success_at = $1, failure_count = 0, last_error = NULL, backoff_until = NULL, next_fetch_at = $2, claimed_until = NULL, claim_token = NULL
+WHERE id = $3 AND claim_token = $4
+`
+
+type RecordFeedSuccessParams struct {
+	UpdatedAt   time.Time
+	NextFetchAt sql.NullTime
+	ID          uuid.UUID
+	ClaimToken  uuid.NullUUID
+}
+
+func (q *Queries) RecordFeedSuccess(ctx context.Context, arg RecordFeedSuccessParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedSuccess,
+		arg.UpdatedAt,
+		arg.NextFetchAt,
+		arg.ID,
+		arg.ClaimToken,
+	)
+	return err
+}
+
+const releaseFeedClaim = `-- name: ReleaseFeedClaim :exec
+UPDATE feeds
+SET claimed_until = NULL, claim_token = NULL
+WHERE id = $1 AND claim_token = $2
+`
+
+type ReleaseFeedClaimParams struct {
+	ID         uuid.UUID
+	ClaimToken uuid.NullUUID
+}
+
+func (q *Queries) ReleaseFeedClaim(ctx context.Context, arg ReleaseFeedClaimParams) error {
+	_, err := q.db.ExecContext(ctx, releaseFeedClaim, arg.ID, arg.ClaimToken)
+	return err
+}
+
+const renewFeedClaims = `-- name: RenewFeedClaims :exec
+UPDATE feeds
+SET claimed_until = $1
+WHERE claim_token = $2
+`
+
+type RenewFeedClaimsParams struct {
+	ClaimedUntil sql.NullTime
+	ClaimToken   uuid.NullUUID
+}
+
+func (q *Queries) RenewFeedClaims(ctx context.Context, arg RenewFeedClaimsParams) error {
+	_, err := q.db.ExecContext(ctx, renewFeedClaims, arg.ClaimedUntil, arg.ClaimToken)
+	return err
+}
+
+const updateFeedCacheHeaders = `-- name: UpdateFeedCacheHeaders :exec
+UPDATE feeds
+SET updated_at = $1, etag = $2, last_modified = $3
+WHERE id = $4
+`
+
+type UpdateFeedCacheHeadersParams struct {
+	UpdatedAt    time.Time
+	Etag         sql.NullString
+	LastModified sql.NullString
+	ID           uuid.UUID
+}
+
+func (q *Queries) UpdateFeedCacheHeaders(ctx context.Context, arg UpdateFeedCacheHeadersParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedCacheHeaders,
+		arg.UpdatedAt,
+		arg.Etag,
+		arg.LastModified,
+		arg.ID,
+	)
+	return err
+}
+
+const updateFeedScheduleHints = `-- name: UpdateFeedScheduleHints :exec
+UPDATE feeds
+SET updated_at = $1, ttl_minutes = $2, skip_hours = $3, skip_days = $4
+WHERE id = $5
+`
+
+type UpdateFeedScheduleHintsParams struct {
+	UpdatedAt  time.Time
+	TtlMinutes sql.NullInt32
+	SkipHours  []int32
+	SkipDays   []string
+	ID         uuid.UUID
+}
+
+func (q *Queries) UpdateFeedScheduleHints(ctx context.Context, arg UpdateFeedScheduleHintsParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedScheduleHints,
+		arg.UpdatedAt,
+		arg.TtlMinutes,
+		pq.Array(arg.SkipHours),
+		pq.Array(arg.SkipDays),
+		arg.ID,
+	)
+	return err
+}
+
+const updateFeedSiteURL = `-- name: UpdateFeedSiteURL :exec
+UPDATE feeds
+SET updated_at = $1, site_url = $2
+WHERE id = $3
+`
+
+type UpdateFeedSiteURLParams struct {
+	UpdatedAt time.Time
+	SiteUrl   sql.NullString
+	ID        uuid.UUID
+}
+
+func (q *Queries) UpdateFeedSiteURL(ctx context.Context, arg UpdateFeedSiteURLParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedSiteURL, arg.UpdatedAt, arg.SiteUrl, arg.ID)
+	return err
+}
+
+const updateFeedURL = `-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET updated_at = $1, url = $2, redirect_url = NULL, redirect_count = 0
+WHERE id = $3
+`
+
+type UpdateFeedURLParams struct {
+	UpdatedAt time.Time
+	Url       string
+	ID        uuid.UUID
+}
+
+func (q *Queries) UpdateFeedURL(ctx context.Context, arg UpdateFeedURLParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedURL, arg.UpdatedAt, arg.Url, arg.ID)
 	return err
 }
@@ -19,6 +19,32 @@ type Feed struct {
 	Url           string
 	UserID        uuid.UUID
 	LastFetchedAt sql.NullTime
+	Etag          sql.NullString
+	LastModified  sql.NullString
+	ClaimedUntil  sql.NullTime
+	ClaimToken    uuid.NullUUID
+	FailureCount  int32
+	LastError     sql.NullString
+	BackoffUntil  sql.NullTime
+	LastSuccessAt sql.NullTime
+	SiteUrl       sql.NullString
+	NumericID     int64
+	NextFetchAt   sql.NullTime
+	TtlMinutes    sql.NullInt32
+	SkipHours     []int32
+	SkipDays      []string
+	RedirectUrl   sql.NullString
+	RedirectCount int32
+	Active        bool
+}
+
+type FeedFetch struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	FeedID     uuid.UUID
+	StatusCode sql.NullInt32
+	ItemCount  sql.NullInt32
+	Error      sql.NullString
 }
 
 type FeedFollow struct {
@@ -27,22 +53,71 @@ type FeedFollow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	Category  sql.NullString
+}
+
+type FeedNotice struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	Message   string
+}
+
+type GreaderToken struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	TokenHash string
 }
 
 type Post struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt time.Time
-	FeedID      uuid.UUID
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Title        string
+	Url          string
+	Description  sql.NullString
+	PublishedAt  time.Time
+	FeedID       uuid.UUID
+	Author       sql.NullString
+	Guid         string
+	ContentHash  string
+	Revisions    int32
+	SearchVector interface{}
+	NumericID    int64
 }
 
-type User struct {
+type PostRead struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+}
+
+type SavedPost struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
-	Name      string
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+}
+
+type User struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Name          string
+	ApiTokenHash  sql.NullString
+	PasswordHash  sql.NullString
+	FeedTokenHash sql.NullString
+	FeverApiKey   sql.NullString
+}
+
+type WebSession struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UserID    uuid.UUID
+	TokenHash string
 }
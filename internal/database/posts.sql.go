@@ -11,10 +11,11 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createPost = `-- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, author, guid, content_hash)
 VALUES (
     $1,
     $2,
@@ -23,9 +24,21 @@ VALUES (
     $5,
     $6,
     $7,
-    $8
+    $8,
+    $9,
+    $10,
+    $11
 )
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+ON CONFLICT (feed_id, guid) DO UPDATE
+SET updated_at = EXCLUDED.updated_at,
+    title = EXCLUDED.title,
+    url = EXCLUDED.url,
+    description = EXCLUDED.description,
+    author = EXCLUDED.author,
+    content_hash = EXCLUDED.content_hash,
+    revisions = posts.revisions + 1
+WHERE posts.content_hash <> EXCLUDED.content_hash
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, author, guid, content_hash, revisions, numeric_id
 `
 
 type CreatePostParams struct {
@@ -37,9 +50,28 @@ type CreatePostParams struct {
 	Description sql.NullString
 	PublishedAt time.Time
 	FeedID      uuid.UUID
+	Author      sql.NullString
+	Guid        string
+	ContentHash string
 }
 
-func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
+type CreatePostRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt time.Time
+	FeedID      uuid.UUID
+	Author      sql.NullString
+	Guid        string
+	ContentHash string
+	Revisions   int32
+	NumericID   int64
+}
+
+func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (CreatePostRow, error) {
 	row := q.db.QueryRowContext(ctx, createPost,
 		arg.ID,
 		arg.CreatedAt,
@@ -49,8 +81,174 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.Description,
 		arg.PublishedAt,
 		arg.FeedID,
+		arg.Author,
+		arg.Guid,
+		arg.ContentHash,
+	)
+	var i CreatePostRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Author,
+		&i.Guid,
+		&i.ContentHash,
+		&i.Revisions,
+		&i.NumericID,
+	)
+	return i, err
+}
+
+const getLegacyPostURLs = `-- name: GetLegacyPostURLs :many
+SELECT url FROM posts
+WHERE feed_id = $1
+AND guid = url
+AND url = ANY($2::text[])
+`
+
+type GetLegacyPostURLsParams struct {
+	FeedID uuid.UUID
+	Urls   []string
+}
+
+func (q *Queries) GetLegacyPostURLs(ctx context.Context, arg GetLegacyPostURLsParams) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getLegacyPostURLs, arg.FeedID, pq.Array(arg.Urls))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return nil, err
+		}
+		items = append(items, url)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPost = `-- name: GetPost :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, author, guid, content_hash, revisions, numeric_id FROM posts
+WHERE id = $1
+`
+
+type GetPostRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt time.Time
+	FeedID      uuid.UUID
+	Author      sql.NullString
+	Guid        string
+	ContentHash string
+	Revisions   int32
+	NumericID   int64
+}
+
+func (q *Queries) GetPost(ctx context.Context, id uuid.UUID) (GetPostRow, error) {
+	row := q.db.QueryRowContext(ctx, getPost, id)
+	var i GetPostRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Author,
+		&i.Guid,
+		&i.ContentHash,
+		&i.Revisions,
+		&i.NumericID,
 	)
-	var i Post
+	return i, err
+}
+
+const getPostByNumericID = `-- name: GetPostByNumericID :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, author, guid, content_hash, revisions, numeric_id FROM posts
+WHERE numeric_id = $1
+`
+
+type GetPostByNumericIDRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt time.Time
+	FeedID      uuid.UUID
+	Author      sql.NullString
+	Guid        string
+	ContentHash string
+	Revisions   int32
+	NumericID   int64
+}
+
+func (q *Queries) GetPostByNumericID(ctx context.Context, numericID int64) (GetPostByNumericIDRow, error) {
+	row := q.db.QueryRowContext(ctx, getPostByNumericID, numericID)
+	var i GetPostByNumericIDRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Author,
+		&i.Guid,
+		&i.ContentHash,
+		&i.Revisions,
+		&i.NumericID,
+	)
+	return i, err
+}
+
+const getPostByURL = `-- name: GetPostByURL :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, author, guid, content_hash, revisions, numeric_id FROM posts
+WHERE url = $1
+ORDER BY published_at DESC
+LIMIT 1
+`
+
+type GetPostByURLRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt time.Time
+	FeedID      uuid.UUID
+	Author      sql.NullString
+	Guid        string
+	ContentHash string
+	Revisions   int32
+	NumericID   int64
+}
+
+func (q *Queries) GetPostByURL(ctx context.Context, url string) (GetPostByURLRow, error) {
+	row := q.db.QueryRowContext(ctx, getPostByURL, url)
+	var i GetPostByURLRow
 	err := row.Scan(
 		&i.ID,
 		&i.CreatedAt,
@@ -60,21 +258,40 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.Author,
+		&i.Guid,
+		&i.ContentHash,
+		&i.Revisions,
+		&i.NumericID,
 	)
 	return i, err
 }
 
 const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT posts.id, posts.created_at, posts.updated_at, title, url, description, published_at, posts.feed_id, feed_follows.id, feed_follows.created_at, feed_follows.updated_at, user_id, feed_follows.feed_id FROM posts
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.guid, posts.content_hash, posts.revisions, posts.numeric_id, feeds.name AS feed_name,
+    EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = posts.id AND post_reads.user_id = $1
+    ) AS is_read
+FROM posts
 INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds ON posts.feed_id = feeds.id
 WHERE feed_follows.user_id = $1
-ORDER BY published_at DESC
-LIMIT $2
+AND ($2::uuid IS NULL OR posts.feed_id = $2)
+AND (NOT $3::boolean OR NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id AND post_reads.user_id = $1
+))
+ORDER BY posts.published_at DESC
+LIMIT $4 OFFSET $5
 `
 
 type GetPostsForUserParams struct {
-	UserID uuid.UUID
-	Limit  int32
+	UserID     uuid.UUID
+	FeedID     uuid.NullUUID
+	UnreadOnly bool
+	PostLimit  int32
+	PostOffset int32
 }
 
 type GetPostsForUserRow struct {
@@ -86,15 +303,23 @@ type GetPostsForUserRow struct {
 	Description sql.NullString
 	PublishedAt time.Time
 	FeedID      uuid.UUID
-	ID_2        uuid.UUID
-	CreatedAt_2 time.Time
-	UpdatedAt_2 time.Time
-	UserID      uuid.UUID
-	FeedID_2    uuid.UUID
+	Author      sql.NullString
+	Guid        string
+	ContentHash string
+	Revisions   int32
+	NumericID   int64
+	FeedName    string
+	IsRead      bool
 }
 
 func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.UserID,
+		arg.FeedID,
+		arg.UnreadOnly,
+		arg.PostLimit,
+		arg.PostOffset,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -111,11 +336,150 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
-			&i.ID_2,
-			&i.CreatedAt_2,
-			&i.UpdatedAt_2,
-			&i.UserID,
-			&i.FeedID_2,
+			&i.Author,
+			&i.Guid,
+			&i.ContentHash,
+			&i.Revisions,
+			&i.NumericID,
+			&i.FeedName,
+			&i.IsRead,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRecentPostTimes = `-- name: GetRecentPostTimes :many
+SELECT published_at FROM posts
+WHERE feed_id = $1
+ORDER BY published_at DESC
+LIMIT $2
+`
+
+type GetRecentPostTimesParams struct {
+	FeedID uuid.UUID
+	Limit  int32
+}
+
+func (q *Queries) GetRecentPostTimes(ctx context.Context, arg GetRecentPostTimesParams) ([]time.Time, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentPostTimes, arg.FeedID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []time.Time
+	for rows.Next() {
+		var published_at time.Time
+		if err := rows.Scan(&published_at); err != nil {
+			return nil, err
+		}
+		items = append(items, published_at)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const movePosts = `-- name: MovePosts :execrows
+UPDATE posts
+SET feed_id = $1
+WHERE feed_id = $2
+AND guid NOT IN (SELECT guid FROM posts WHERE feed_id = $1)
+`
+
+type MovePostsParams struct {
+	NewFeedID uuid.UUID
+	OldFeedID uuid.UUID
+}
+
+func (q *Queries) MovePosts(ctx context.Context, arg MovePostsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, movePosts, arg.NewFeedID, arg.OldFeedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const replaceLegacyPostGUID = `-- name: ReplaceLegacyPostGUID :exec
+UPDATE posts
+SET guid = $1
+WHERE feed_id = $2
+AND url = $3
+AND guid = url
+AND NOT EXISTS (
+    SELECT 1 FROM posts AS existing
+    WHERE existing.feed_id = $2 AND existing.guid = $1
+)
+`
+
+type ReplaceLegacyPostGUIDParams struct {
+	Guid   string
+	FeedID uuid.UUID
+	Url    string
+}
+
+func (q *Queries) ReplaceLegacyPostGUID(ctx context.Context, arg ReplaceLegacyPostGUIDParams) error {
+	_, err := q.db.ExecContext(ctx, replaceLegacyPostGUID, arg.Guid, arg.FeedID, arg.Url)
+	return err
+}
+
+const searchPostsForUser = `-- name: SearchPostsForUser :many
+SELECT posts.id, posts.title, posts.url, posts.description, posts.published_at, feeds.name AS feed_name,
+    ts_rank(websearch_to_tsquery('english', $1)) AS rank
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE feed_follows.user_id = $2
+AND posts.search_vector @@ websearch_to_tsquery('english', $1)
+ORDER BY rank DESC, posts.published_at DESC
+LIMIT $3
+`
+
+type SearchPostsForUserParams struct {
+	Query     string
+	UserID    uuid.UUID
+	PostLimit int32
+}
+
+type SearchPostsForUserRow struct {
+	ID          uuid.UUID
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt time.Time
+	FeedName    string
+	Rank        float32
+}
+
+func (q *Queries) SearchPostsForUser(ctx context.Context, arg SearchPostsForUserParams) ([]SearchPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchPostsForUser, arg.Query, arg.UserID, arg.PostLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchPostsForUserRow
+	for rows.Next() {
+		var i SearchPostsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedName,
+			&i.Rank,
 		); err != nil {
 			return nil, err
 		}
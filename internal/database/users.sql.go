@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,7 +21,7 @@ VALUES (
     $3,
     $4
 )
-RETURNING id, created_at, updated_at, name
+RETURNING id, created_at, updated_at, name, api_token_hash, password_hash, feed_token_hash, fever_api_key
 `
 
 type CreateUserParams struct {
@@ -43,12 +44,16 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
+		&i.ApiTokenHash,
+		&i.PasswordHash,
+		&i.FeedTokenHash,
+		&i.FeverApiKey,
 	)
 	return i, err
 }
 
 const getUser = `-- name: GetUser :one
-SELECT id, created_at, updated_at, name FROM users 
+SELECT id, created_at, updated_at, name, api_token_hash, password_hash, feed_token_hash, fever_api_key FROM users 
 WHERE name = $1
 `
 
@@ -60,6 +65,73 @@ func (q *Queries) GetUser(ctx context.Context, name string) (User, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
+		&i.ApiTokenHash,
+		&i.PasswordHash,
+		&i.FeedTokenHash,
+		&i.FeverApiKey,
+	)
+	return i, err
+}
+
+const getUserByAPITokenHash = `-- name: GetUserByAPITokenHash :one
+SELECT id, created_at, updated_at, name, api_token_hash, password_hash, feed_token_hash, fever_api_key FROM users
+WHERE api_token_hash = $1
+`
+
+func (q *Queries) GetUserByAPITokenHash(ctx context.Context, apiTokenHash sql.NullString) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByAPITokenHash, apiTokenHash)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.ApiTokenHash,
+		&i.PasswordHash,
+		&i.FeedTokenHash,
+		&i.FeverApiKey,
+	)
+	return i, err
+}
+
+const getUserByFeedTokenHash = `-- name: GetUserByFeedTokenHash :one
+SELECT id, created_at, updated_at, name, api_token_hash, password_hash, feed_token_hash, fever_api_key FROM users
+WHERE feed_token_hash = $1
+`
+
+func (q *Queries) GetUserByFeedTokenHash(ctx context.Context, feedTokenHash sql.NullString) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByFeedTokenHash, feedTokenHash)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.ApiTokenHash,
+		&i.PasswordHash,
+		&i.FeedTokenHash,
+		&i.FeverApiKey,
+	)
+	return i, err
+}
+
+const getUserByFeverAPIKey = `-- name: GetUserByFeverAPIKey :one
+SELECT id, created_at, updated_at, name, api_token_hash, password_hash, feed_token_hash, fever_api_key FROM users
+WHERE fever_api_key = $1
+`
+
+func (q *Queries) GetUserByFeverAPIKey(ctx context.Context, feverApiKey sql.NullString) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByFeverAPIKey, feverApiKey)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.ApiTokenHash,
+		&i.PasswordHash,
+		&i.FeedTokenHash,
+		&i.FeverApiKey,
 	)
 	return i, err
 }
@@ -91,6 +163,49 @@ func (q *Queries) ListUsers(ctx context.Context) ([]string, error) {
 	return items, nil
 }
 
+const listUsersPage = `-- name: ListUsersPage :many
+SELECT id, created_at, updated_at, name, api_token_hash, password_hash, feed_token_hash, fever_api_key FROM users
+ORDER BY name ASC
+LIMIT $1 OFFSET $2
+`
+
+type ListUsersPageParams struct {
+	Limit  int32
+	Offset int32
+}
+
+func (q *Queries) ListUsersPage(ctx context.Context, arg ListUsersPageParams) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsersPage, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.ApiTokenHash,
+			&i.PasswordHash,
+			&i.FeedTokenHash,
+			&i.FeverApiKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const resetUsers = `-- name: ResetUsers :exec
 DELETE FROM users
 `
@@ -99,3 +214,60 @@ func (q *Queries) ResetUsers(ctx context.Context) error {
 	_, err := q.db.ExecContext(ctx, resetUsers)
 	return err
 }
+
+const updateUserAPITokenHash = `-- name: UpdateUserAPITokenHash :exec
+UPDATE users
+SET updated_at = $2, api_token_hash = $3
+WHERE id = $1
+`
+
+type UpdateUserAPITokenHashParams struct {
+	ID           uuid.UUID
+	UpdatedAt    time.Time
+	ApiTokenHash sql.NullString
+}
+
+func (q *Queries) UpdateUserAPITokenHash(ctx context.Context, arg UpdateUserAPITokenHashParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserAPITokenHash, arg.ID, arg.UpdatedAt, arg.ApiTokenHash)
+	return err
+}
+
+const updateUserFeedTokenHash = `-- name: UpdateUserFeedTokenHash :exec
+UPDATE users
+SET updated_at = $2, feed_token_hash = $3
+WHERE id = $1
+`
+
+type UpdateUserFeedTokenHashParams struct {
+	ID            uuid.UUID
+	UpdatedAt     time.Time
+	FeedTokenHash sql.NullString
+}
+
+func (q *Queries) UpdateUserFeedTokenHash(ctx context.Context, arg UpdateUserFeedTokenHashParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserFeedTokenHash, arg.ID, arg.UpdatedAt, arg.FeedTokenHash)
+	return err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET updated_at = $2, password_hash = $3, fever_api_key = $4
+WHERE id = $1
+`
+
+type UpdateUserPasswordParams struct {
+	ID           uuid.UUID
+	UpdatedAt    time.Time
+	PasswordHash sql.NullString
+	FeverApiKey  sql.NullString
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword,
+		arg.ID,
+		arg.UpdatedAt,
+		arg.PasswordHash,
+		arg.FeverApiKey,
+	)
+	return err
+}
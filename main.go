@@ -11,13 +11,18 @@ import (
 )
 
 func main() {
-	if len(os.Args) < 2 {
+	format, args, err := config.ExtractFormat(os.Args[1:])
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		os.Exit(1)
+	}
+	if len(args) < 1 {
 		fmt.Println("Error: no command argument provided.")
 		os.Exit(1)
 	}
 	user_cmd := config.Command{
-		Name: os.Args[1],
-		Args: os.Args[2:],
+		Name: args[0],
+		Args: args[1:],
 	}
 	config_struct := config.Read()
 	db, err := sql.Open("postgres", config_struct.DBUrl)
@@ -27,8 +32,10 @@ func main() {
 	}
 	var main_state config.State
 	main_state.Cfg = &config_struct
+	main_state.Format = format
 	dbQueries := database.New(db)
 	main_state.Db = dbQueries
+	main_state.Conn = db
 	command_registry := config.Commands{
 		Registry: make(map[string]func(*config.State, config.Command) error),
 	}
@@ -36,13 +43,29 @@ func main() {
 	command_registry.Register("register", config.HandlerRegister)
 	command_registry.Register("reset", config.HandlerReset)
 	command_registry.Register("users", config.HandlerListUsers)
+	command_registry.Register("setpassword", config.MiddlewareLoggedIn(config.HandlerSetPassword))
 	command_registry.Register("agg", config.HandlerAgg)
+	command_registry.Register("serve", config.HandlerServe)
+	command_registry.Register("web", config.HandlerWeb)
 	command_registry.Register("addfeed", config.MiddlewareLoggedIn(config.HandlerAddFeed))
 	command_registry.Register("feeds", config.HandlerFeeds)
+	command_registry.Register("feedhealth", config.HandlerFeedHealth)
 	command_registry.Register("follow", config.MiddlewareLoggedIn(config.HandlerFollow))
 	command_registry.Register("following", config.MiddlewareLoggedIn(config.HandlerFollowing))
 	command_registry.Register("unfollow", config.MiddlewareLoggedIn(config.HandlerUnfollow))
+	command_registry.Register("import", config.MiddlewareLoggedIn(config.HandlerImport))
+	command_registry.Register("export", config.MiddlewareLoggedIn(config.HandlerExport))
+	command_registry.Register("publish", config.MiddlewareLoggedIn(config.HandlerPublish))
+	command_registry.Register("feedtoken", config.MiddlewareLoggedIn(config.HandlerFeedToken))
+	command_registry.Register("apitoken", config.MiddlewareLoggedIn(config.HandlerAPIToken))
 	command_registry.Register("browse", config.MiddlewareLoggedIn(config.HandlerBrowse))
+	command_registry.Register("search", config.MiddlewareLoggedIn(config.HandlerSearch))
+	command_registry.Register("read", config.MiddlewareLoggedIn(config.HandlerRead))
+	command_registry.Register("unread", config.MiddlewareLoggedIn(config.HandlerUnread))
+	command_registry.Register("markallread", config.MiddlewareLoggedIn(config.HandlerMarkAllRead))
+	command_registry.Register("save", config.MiddlewareLoggedIn(config.HandlerSave))
+	command_registry.Register("unsave", config.MiddlewareLoggedIn(config.HandlerUnsave))
+	command_registry.Register("saved", config.MiddlewareLoggedIn(config.HandlerSaved))
 	err1 := command_registry.Run(&main_state, user_cmd)
 	if err1 != nil {
 		fmt.Println(err1)
@@ -0,0 +1,29 @@
+-- name: CreateFeedFetch :exec
+INSERT INTO feed_fetches (id, created_at, feed_id, status_code, item_count, error)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+);
+
+-- name: GetFeedHealth :many
+SELECT feeds.id, feeds.name, feeds.url, feeds.last_success_at, feeds.last_error, feeds.failure_count,
+    COALESCE(AVG(feed_fetches.item_count), 0)::float AS avg_items
+FROM feeds
+LEFT JOIN feed_fetches ON feed_fetches.feed_id = feeds.id
+GROUP BY feeds.id
+ORDER BY feeds.name;
+
+-- name: GetRecentFeedFetches :many
+SELECT * FROM feed_fetches
+WHERE feed_id = $1
+ORDER BY created_at DESC
+LIMIT $2;
+
+-- name: MoveFeedFetches :exec
+UPDATE feed_fetches
+SET feed_id = @new_feed_id
+WHERE feed_id = @old_feed_id;
@@ -1,12 +1,13 @@
 -- name: CreateFeedFollow :one
 WITH inserted_feed_follow AS (
-    INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
+    INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id, category)
     VALUES (
         $1,
         $2,
         $3,
         $4,
-        $5
+        $5,
+        $6
     )
     RETURNING *
 )
@@ -16,12 +17,38 @@ INNER JOIN feeds ON inserted_feed_follow.feed_id = feeds.id
 INNER JOIN users ON inserted_feed_follow.user_id = users.id;
 
 -- name: GetFeedFollowsForUser :many
-SELECT feed_follows.*, feeds.name AS feed_name, users.name AS user_name
+SELECT feed_follows.*, feeds.name AS feed_name, feeds.url AS feed_url, feeds.site_url AS feed_site_url, users.name AS user_name
 FROM feed_follows
 INNER JOIN feeds ON feed_follows.feed_id = feeds.id
 INNER JOIN users ON feed_follows.user_id = users.id
 WHERE feed_follows.user_id = $1; 
 
+-- name: ListFeedFollowsPage :many
+SELECT feed_follows.id, feed_follows.created_at, feed_follows.feed_id, feed_follows.category, feeds.name AS feed_name, feeds.url AS feed_url
+FROM feed_follows
+INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+ORDER BY feed_follows.created_at ASC, feed_follows.id ASC
+LIMIT $2 OFFSET $3;
+
 -- name: DeleteFeedFollow :exec
 DELETE FROM feed_follows
-WHERE user_id = $1 AND feed_id = $2;
\ No newline at end of file
+WHERE user_id = $1 AND feed_id = $2;
+
+-- name: UpdateFeedFollowCategory :exec
+UPDATE feed_follows
+SET updated_at = $1, category = $2
+WHERE user_id = $3 AND feed_id = $4;
+
+-- name: GetSubscriptionsForUser :many
+SELECT feeds.numeric_id, feeds.name, feeds.url, feeds.site_url, feeds.last_success_at, feed_follows.category
+FROM feed_follows
+INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+ORDER BY feeds.numeric_id;
+
+-- name: MoveFeedFollows :exec
+UPDATE feed_follows
+SET updated_at = @updated_at, feed_id = @new_feed_id
+WHERE feed_id = @old_feed_id
+AND user_id NOT IN (SELECT user_id FROM feed_follows WHERE feed_id = @new_feed_id);
@@ -0,0 +1,10 @@
+-- name: CreateFeedNotices :exec
+INSERT INTO feed_notices (id, created_at, user_id, message)
+SELECT gen_random_uuid(), $1, feed_follows.user_id, $2
+FROM feed_follows
+WHERE feed_follows.feed_id = $3;
+
+-- name: TakeFeedNoticesForUser :many
+DELETE FROM feed_notices
+WHERE user_id = $1
+RETURNING message;
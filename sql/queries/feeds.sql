@@ -1,12 +1,13 @@
 -- name: CreateFeed :one
-INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id, site_url)
 VALUES (
     $1,
     $2,
     $3,
     $4,
     $5,
-    $6
+    $6,
+    $7
 )
 RETURNING *;
 
@@ -15,6 +16,13 @@ SELECT f.name, f.url, u.name
 FROM feeds f
 LEFT JOIN users u ON f.user_id = u.id;
 
+-- name: ListFeedsPage :many
+SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.site_url, feeds.user_id, feeds.last_fetched_at, feeds.last_success_at, users.name AS user_name
+FROM feeds
+LEFT JOIN users ON feeds.user_id = users.id
+ORDER BY feeds.created_at ASC
+LIMIT $1 OFFSET $2;
+
 -- name: GetFeed :one
 SELECT * FROM feeds
 WHERE url = $1;
@@ -23,12 +31,76 @@ WHERE url = $1;
 SELECT * FROM feeds
 WHERE id = $1;
 
--- name: MarkFeedFetched :exec
+-- name: ClaimFeedsToFetch :many
+UPDATE feeds
+SET updated_at = $1, last_fetched_at = $2, claimed_until = $3, claim_token = $4
+WHERE id IN (
+    SELECT id FROM feeds
+    WHERE active
+    AND (claimed_until IS NULL OR claimed_until <= $1)
+    AND (next_fetch_at IS NULL OR next_fetch_at <= $1)
+    AND (backoff_until IS NULL OR backoff_until <= $1)
+    ORDER BY next_fetch_at ASC NULLS FIRST, last_fetched_at ASC NULLS FIRST
+    LIMIT $5
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: DeactivateFeed :exec
+UPDATE feeds
+SET updated_at = $1, active = FALSE, last_error = $2, backoff_until = NULL, next_fetch_at = NULL, claimed_until = NULL, claim_token = NULL
+WHERE id = $3 AND claim_token = $4;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds
+WHERE id = $1;
+
+-- name: ReleaseFeedClaim :exec
+UPDATE feeds
+SET claimed_until = NULL, claim_token = NULL
+WHERE id = $1 AND claim_token = $2;
+
+-- name: PostponeFeed :exec
 UPDATE feeds
-SET updated_at = $1, last_fetched_at = $2
+SET updated_at = $1, next_fetch_at = $2, claimed_until = NULL, claim_token = NULL
+WHERE id = $3 AND claim_token = $4;
+
+-- name: RecordFeedFailure :exec
+UPDATE feeds
+SET updated_at = $1, failure_count = failure_count + 1, last_error = $2, backoff_until = $3, claimed_until = NULL, claim_token = NULL
+WHERE id = $4 AND claim_token = $5;
+
+-- name: RecordFeedRedirect :exec
+UPDATE feeds
+SET updated_at = $1, redirect_url = $2, redirect_count = $3
+WHERE id = $4;
+
+-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET updated_at = $1, last_success_at = $1, failure_count = 0, last_error = NULL, backoff_until = NULL, next_fetch_at = $2, claimed_until = NULL, claim_token = NULL
+WHERE id = $3 AND claim_token = $4;
+
+-- name: RenewFeedClaims :exec
+UPDATE feeds
+SET claimed_until = $1
+WHERE claim_token = $2;
+
+-- name: UpdateFeedCacheHeaders :exec
+UPDATE feeds
+SET updated_at = $1, etag = $2, last_modified = $3
+WHERE id = $4;
+
+-- name: UpdateFeedURL :exec
+UPDATE feeds
+SET updated_at = $1, url = $2, redirect_url = NULL, redirect_count = 0
 WHERE id = $3;
 
--- name: GetNextFeedToFetch :one
-SELECT * FROM feeds
-ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1;
\ No newline at end of file
+-- name: UpdateFeedScheduleHints :exec
+UPDATE feeds
+SET updated_at = $1, ttl_minutes = $2, skip_hours = $3, skip_days = $4
+WHERE id = $5;
+
+-- name: UpdateFeedSiteURL :exec
+UPDATE feeds
+SET updated_at = $1, site_url = $2
+WHERE id = $3;
\ No newline at end of file
@@ -0,0 +1,43 @@
+-- name: GetFeverItemsForUser :many
+SELECT posts.numeric_id, feeds.numeric_id AS feed_numeric_id, posts.title, posts.author, posts.description, posts.url, posts.published_at,
+    EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = posts.id AND post_reads.user_id = @user_id
+    ) AS is_read,
+    EXISTS (
+        SELECT 1 FROM saved_posts
+        WHERE saved_posts.post_id = posts.id AND saved_posts.user_id = @user_id
+    ) AS is_saved
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE feed_follows.user_id = @user_id
+AND (sqlc.narg(since_id)::bigint IS NULL OR posts.numeric_id > sqlc.narg(since_id))
+AND (sqlc.narg(max_id)::bigint IS NULL OR posts.numeric_id < sqlc.narg(max_id))
+AND (sqlc.narg(with_ids)::bigint[] IS NULL OR posts.numeric_id = ANY(sqlc.narg(with_ids)::bigint[]))
+ORDER BY CASE WHEN sqlc.narg(max_id)::bigint IS NULL THEN posts.numeric_id ELSE -posts.numeric_id END
+LIMIT @item_limit;
+
+-- name: CountPostsForUser :one
+SELECT COUNT(*)
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1;
+
+-- name: GetUnreadPostIDsForUser :many
+SELECT posts.numeric_id
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+AND NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id AND post_reads.user_id = $1
+)
+ORDER BY posts.numeric_id;
+
+-- name: GetSavedPostIDsForUser :many
+SELECT posts.numeric_id
+FROM saved_posts
+INNER JOIN posts ON saved_posts.post_id = posts.id
+WHERE saved_posts.user_id = $1
+ORDER BY posts.numeric_id;
@@ -0,0 +1,67 @@
+-- name: GetStreamItemsForUser :many
+SELECT posts.numeric_id, posts.title, posts.author, posts.description, posts.url, posts.published_at, posts.updated_at,
+    feeds.numeric_id AS feed_numeric_id, feeds.name AS feed_name, feeds.site_url AS feed_site_url, feed_follows.category,
+    EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = posts.id AND post_reads.user_id = @user_id
+    ) AS is_read,
+    EXISTS (
+        SELECT 1 FROM saved_posts
+        WHERE saved_posts.post_id = posts.id AND saved_posts.user_id = @user_id
+    ) AS is_saved
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE feed_follows.user_id = @user_id
+AND (sqlc.narg(feed_numeric_id)::bigint IS NULL OR feeds.numeric_id = sqlc.narg(feed_numeric_id))
+AND (sqlc.narg(category)::text IS NULL OR feed_follows.category = sqlc.narg(category))
+AND (sqlc.narg(item_ids)::bigint[] IS NULL OR posts.numeric_id = ANY(sqlc.narg(item_ids)::bigint[]))
+AND (NOT @starred_only::boolean OR EXISTS (
+    SELECT 1 FROM saved_posts
+    WHERE saved_posts.post_id = posts.id AND saved_posts.user_id = @user_id
+))
+AND (NOT @read_only::boolean OR EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id AND post_reads.user_id = @user_id
+))
+AND (NOT @unread_only::boolean OR NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id AND post_reads.user_id = @user_id
+))
+AND (sqlc.narg(newer_than)::timestamp IS NULL OR posts.published_at >= sqlc.narg(newer_than))
+AND (sqlc.narg(older_than)::timestamp IS NULL OR posts.published_at < sqlc.narg(older_than))
+ORDER BY
+    CASE WHEN @oldest_first::boolean THEN posts.published_at END ASC,
+    posts.published_at DESC,
+    posts.numeric_id DESC
+LIMIT @item_limit OFFSET @item_offset;
+
+-- name: GetUnreadCountsForUser :many
+SELECT feeds.numeric_id, feed_follows.category, COUNT(posts.id) AS unread_count, MAX(posts.published_at)::timestamp AS newest_published_at
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+AND NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id AND post_reads.user_id = $1
+)
+GROUP BY feeds.numeric_id, feed_follows.category;
+
+-- name: CreateGReaderToken :exec
+INSERT INTO greader_tokens (id, created_at, user_id, token_hash)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+);
+
+-- name: GetUserByGReaderToken :one
+SELECT users.* FROM greader_tokens
+INNER JOIN users ON greader_tokens.user_id = users.id
+WHERE greader_tokens.token_hash = $1;
+
+-- name: DeleteGReaderTokensForUser :exec
+DELETE FROM greader_tokens
+WHERE user_id = $1;
@@ -0,0 +1,50 @@
+-- name: MarkPostRead :exec
+INSERT INTO post_reads (id, created_at, updated_at, user_id, post_id)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: MarkPostUnread :exec
+DELETE FROM post_reads
+WHERE user_id = $1 AND post_id = $2;
+
+-- name: MarkAllPostsRead :execrows
+INSERT INTO post_reads (id, created_at, updated_at, user_id, post_id)
+SELECT gen_random_uuid(), $1, $1, feed_follows.user_id, posts.id
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = $2
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: MarkFeedPostsRead :execrows
+INSERT INTO post_reads (id, created_at, updated_at, user_id, post_id)
+SELECT gen_random_uuid(), $1, $1, feed_follows.user_id, posts.id
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = $2 AND posts.feed_id = $3
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: MarkPostsReadBefore :execrows
+INSERT INTO post_reads (id, created_at, updated_at, user_id, post_id)
+SELECT gen_random_uuid(), @created_at, @created_at, feed_follows.user_id, posts.id
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE feed_follows.user_id = @user_id
+AND (sqlc.narg(feed_numeric_id)::bigint IS NULL OR feeds.numeric_id = sqlc.narg(feed_numeric_id))
+AND (sqlc.narg(category)::text IS NULL OR feed_follows.category = sqlc.narg(category))
+AND posts.created_at <= @before
+ON CONFLICT (user_id, post_id) DO NOTHING;
+-- name: MovePostReads :exec
+INSERT INTO post_reads (id, created_at, updated_at, user_id, post_id)
+SELECT gen_random_uuid(), post_reads.created_at, post_reads.updated_at, post_reads.user_id, target.id
+FROM post_reads
+INNER JOIN posts AS source ON post_reads.post_id = source.id
+INNER JOIN posts AS target ON target.feed_id = @new_feed_id AND target.guid = source.guid
+WHERE source.feed_id = @old_feed_id
+ON CONFLICT (user_id, post_id) DO NOTHING;
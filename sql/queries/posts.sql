@@ -1,5 +1,5 @@
 -- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, author, guid, content_hash)
 VALUES (
     $1,
     $2,
@@ -8,13 +8,89 @@ VALUES (
     $5,
     $6,
     $7,
-    $8
+    $8,
+    $9,
+    $10,
+    $11
 )
-RETURNING *;
+ON CONFLICT (feed_id, guid) DO UPDATE
+SET updated_at = EXCLUDED.updated_at,
+    title = EXCLUDED.title,
+    url = EXCLUDED.url,
+    description = EXCLUDED.description,
+    author = EXCLUDED.author,
+    content_hash = EXCLUDED.content_hash,
+    revisions = posts.revisions + 1
+WHERE posts.content_hash <> EXCLUDED.content_hash
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, author, guid, content_hash, revisions, numeric_id;
+
+-- name: GetLegacyPostURLs :many
+SELECT url FROM posts
+WHERE feed_id = @feed_id
+AND guid = url
+AND url = ANY(@urls::text[]);
+
+-- name: ReplaceLegacyPostGUID :exec
+UPDATE posts
+SET guid = @guid
+WHERE feed_id = @feed_id
+AND url = @url
+AND guid = url
+AND NOT EXISTS (
+    SELECT 1 FROM posts AS existing
+    WHERE existing.feed_id = @feed_id AND existing.guid = @guid
+);
 
 -- name: GetPostsForUser :many
-SELECT * FROM posts
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.guid, posts.content_hash, posts.revisions, posts.numeric_id, feeds.name AS feed_name,
+    EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.post_id = posts.id AND post_reads.user_id = @user_id
+    ) AS is_read
+FROM posts
 INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
-WHERE feed_follows.user_id = $1
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE feed_follows.user_id = @user_id
+AND (sqlc.narg(feed_id)::uuid IS NULL OR posts.feed_id = sqlc.narg(feed_id))
+AND (NOT @unread_only::boolean OR NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.post_id = posts.id AND post_reads.user_id = @user_id
+))
+ORDER BY posts.published_at DESC
+LIMIT @post_limit OFFSET @post_offset;
+
+-- name: GetPost :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, author, guid, content_hash, revisions, numeric_id FROM posts
+WHERE id = $1;
+
+-- name: GetPostByNumericID :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, author, guid, content_hash, revisions, numeric_id FROM posts
+WHERE numeric_id = $1;
+
+-- name: GetPostByURL :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, author, guid, content_hash, revisions, numeric_id FROM posts
+WHERE url = $1
 ORDER BY published_at DESC
-LIMIT $2;
\ No newline at end of file
+LIMIT 1;
+
+-- name: GetRecentPostTimes :many
+SELECT published_at FROM posts
+WHERE feed_id = $1
+ORDER BY published_at DESC
+LIMIT $2;
+
+-- name: SearchPostsForUser :many
+SELECT posts.id, posts.title, posts.url, posts.description, posts.published_at, feeds.name AS feed_name,
+    ts_rank(posts.search_vector, websearch_to_tsquery('english', @query)) AS rank
+FROM posts
+INNER JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE feed_follows.user_id = @user_id
+AND posts.search_vector @@ websearch_to_tsquery('english', @query)
+ORDER BY rank DESC, posts.published_at DESC
+LIMIT @post_limit;
+-- name: MovePosts :execrows
+UPDATE posts
+SET feed_id = @new_feed_id
+WHERE feed_id = @old_feed_id
+AND guid NOT IN (SELECT guid FROM posts WHERE feed_id = @new_feed_id);
@@ -0,0 +1,31 @@
+-- name: SavePost :exec
+INSERT INTO saved_posts (id, created_at, updated_at, user_id, post_id)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: UnsavePost :exec
+DELETE FROM saved_posts
+WHERE user_id = $1 AND post_id = $2;
+
+-- name: GetSavedPostsForUser :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.guid, posts.content_hash, posts.revisions, posts.numeric_id, feeds.name AS feed_name, saved_posts.created_at AS saved_at
+FROM saved_posts
+INNER JOIN posts ON saved_posts.post_id = posts.id
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE saved_posts.user_id = $1
+ORDER BY saved_posts.created_at DESC;
+
+-- name: MoveSavedPosts :exec
+INSERT INTO saved_posts (id, created_at, updated_at, user_id, post_id)
+SELECT gen_random_uuid(), saved_posts.created_at, saved_posts.updated_at, saved_posts.user_id, target.id
+FROM saved_posts
+INNER JOIN posts AS source ON saved_posts.post_id = source.id
+INNER JOIN posts AS target ON target.feed_id = @new_feed_id AND target.guid = source.guid
+WHERE source.feed_id = @old_feed_id
+ON CONFLICT (user_id, post_id) DO NOTHING;
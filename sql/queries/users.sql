@@ -16,4 +16,36 @@ WHERE name = $1;
 DELETE FROM users;
 
 -- name: ListUsers :many
-SELECT name FROM users;
\ No newline at end of file
+SELECT name FROM users;
+
+-- name: ListUsersPage :many
+SELECT * FROM users
+ORDER BY name ASC
+LIMIT $1 OFFSET $2;
+
+-- name: GetUserByAPITokenHash :one
+SELECT * FROM users
+WHERE api_token_hash = $1;
+
+-- name: UpdateUserAPITokenHash :exec
+UPDATE users
+SET updated_at = $2, api_token_hash = $3
+WHERE id = $1;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET updated_at = $2, password_hash = $3, fever_api_key = $4
+WHERE id = $1;
+
+-- name: GetUserByFeedTokenHash :one
+SELECT * FROM users
+WHERE feed_token_hash = $1;
+
+-- name: UpdateUserFeedTokenHash :exec
+UPDATE users
+SET updated_at = $2, feed_token_hash = $3
+WHERE id = $1;
+
+-- name: GetUserByFeverAPIKey :one
+SELECT * FROM users
+WHERE fever_api_key = $1;
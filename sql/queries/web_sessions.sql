@@ -0,0 +1,22 @@
+-- name: CreateWebSession :exec
+INSERT INTO web_sessions (id, created_at, expires_at, user_id, token_hash)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+);
+
+-- name: GetUserByWebSession :one
+SELECT users.* FROM web_sessions
+INNER JOIN users ON web_sessions.user_id = users.id
+WHERE web_sessions.token_hash = $1 AND web_sessions.expires_at > $2;
+
+-- name: DeleteWebSession :exec
+DELETE FROM web_sessions
+WHERE token_hash = $1;
+
+-- name: DeleteWebSessionsForUser :exec
+DELETE FROM web_sessions
+WHERE user_id = $1;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN claimed_until TIMESTAMP,
+ADD COLUMN claim_token UUID;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN claim_token,
+DROP COLUMN claimed_until;
@@ -0,0 +1,21 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN last_success_at TIMESTAMP;
+
+CREATE TABLE feed_fetches (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    feed_id UUID NOT NULL,
+    status_code INTEGER,
+    item_count INTEGER,
+    error TEXT,
+    FOREIGN KEY (feed_id) REFERENCES feeds(id) ON DELETE CASCADE
+);
+
+CREATE INDEX feed_fetches_feed_id_created_at_idx ON feed_fetches (feed_id, created_at DESC);
+
+-- +goose Down
+DROP TABLE feed_fetches;
+
+ALTER TABLE feeds
+DROP COLUMN last_success_at;
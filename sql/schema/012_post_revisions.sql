@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN content_hash TEXT,
+ADD COLUMN revisions INTEGER NOT NULL DEFAULT 0;
+
+UPDATE posts
+SET content_hash = encode(sha256(convert_to(title || E'\n' || COALESCE(description, ''), 'UTF8')), 'hex');
+
+ALTER TABLE posts
+ALTER COLUMN content_hash SET NOT NULL;
+
+-- +goose Down
+ALTER TABLE posts
+DROP COLUMN content_hash,
+DROP COLUMN revisions;
@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN site_url TEXT;
+
+ALTER TABLE feed_follows
+ADD COLUMN category TEXT;
+
+-- +goose Down
+ALTER TABLE feed_follows
+DROP COLUMN category;
+
+ALTER TABLE feeds
+DROP COLUMN site_url;
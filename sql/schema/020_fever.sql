@@ -0,0 +1,19 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN numeric_id BIGINT GENERATED ALWAYS AS IDENTITY UNIQUE;
+
+ALTER TABLE posts
+ADD COLUMN numeric_id BIGINT GENERATED ALWAYS AS IDENTITY UNIQUE;
+
+ALTER TABLE users
+ADD COLUMN fever_api_key TEXT UNIQUE;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN fever_api_key;
+
+ALTER TABLE posts
+DROP COLUMN numeric_id;
+
+ALTER TABLE feeds
+DROP COLUMN numeric_id;